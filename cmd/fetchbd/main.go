@@ -30,7 +30,7 @@ func getInfoEx(api *network.BizApi) (error, map[string][]*network.InfoExItem) {
 }
 
 func tryGetInfoEx(host string) (error, map[string][]*network.InfoExItem) {
-	err, api := network.CreateBizApi(host)
+	err, api := network.CreateBizApiWithHosts(network.ParseHosts(host))
 	if err != nil {
 		panic(err)
 	}
@@ -85,7 +85,7 @@ func saveFormat2(result map[string][]*network.InfoExItem, filePath string) {
 }
 
 func main() {
-	host := flag.String("host", HOST, "服务器地址")
+	host := flag.String("host", HOST, "服务器地址，多个以逗号分割")
 	filePath := flag.String("output", "./info_ex.json", "文件名")
 	saveFormat := flag.Int("format", 1, "文件保存格式")
 	flag.Parse()
@@ -33,7 +33,7 @@ type record struct {
 
 
 func fetchLatestMinuteData(host string, offset int, n int, codes []string) (error, map[string][]*record) {
-	err, api := network.CreateBizApi(host)
+	err, api := network.CreateBizApiWithHosts(network.ParseHosts(host))
 	if err != nil {
 		return err, nil
 	}
@@ -191,7 +191,7 @@ func runOnceForDaemon(date string, host string, offset int, stockCodes []string,
 }
 
 func main() {
-	host := flag.String("host", HOST, "服务器地址")
+	host := flag.String("host", HOST, "服务器地址，多个以逗号分割")
 	stockCode := flag.String("stock-code", "", "股票代码，以都好分割")
 	count := flag.Int("count", 5, "获取最近的K线数量")
 	offset := flag.Int("offset", 0, "从倒数第一根K线开始获取")
@@ -13,9 +13,10 @@ const (
 
 func main() {
 	output := flag.String("output", "temp", "Directory save file to")
+	host := flag.String("host", HOST, "Server address, separate multiple servers by comma")
 	flag.Parse()
 
-	err, api := network.CreateBizApi(HOST)
+	err, api := network.CreateBizApiWithHosts(network.ParseHosts(*host))
 	if err != nil {
 		fmt.Errorf("[ERROR] Connect server fail, error: %+v", err)
 		os.Exit(1)
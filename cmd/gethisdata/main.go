@@ -23,6 +23,7 @@ func main() {
 	startDate := flag.Int("start-date", 0, "Start date to get data")
 	dataDir := flag.String("data-dir", "data", "Data directory")
 	smart := flag.Bool("smart", false, "Data directory")
+	host := flag.String("host", HOST_ONLY, "Server address, separate multiple servers by comma")
 	flag.Parse()
 
 	err, dp := period.PeriodFromString(*periodStr)
@@ -32,7 +33,7 @@ func main() {
 		return
 	}
 
-	err, api := network.CreateBizApi(HOST_ONLY)
+	err, api := network.CreateBizApiWithHosts(network.ParseHosts(*host))
 	chk(err)
 	defer api.Cleanup()
 	api.SetWorkDir(*dataDir)
@@ -6,7 +6,9 @@ import (
 	"os"
 	"errors"
 	"bufio"
+	"bytes"
 	"encoding/hex"
+	"encoding/json"
 	"github.com/stephenlyu/TdxProtocol/network"
 	"strings"
 )
@@ -18,20 +20,124 @@ func chk(err error) {
 	}
 }
 
-func parseBid(text string) {
-	data, _ := hex.DecodeString(text)
-	parser1 := network.NewRespParser(data)
-	req := network.Header{
-		Cmd: parser1.GetCmd(),
-		SeqId: parser1.GetSeqId(),
+// 已解析的请求，用于解析对应的响应
+var requests = map[uint32]network.Request{}
+
+func parseReq(data []byte) {
+	err, req := network.ParseReq(data)
+	if err != nil {
+		fmt.Printf("[Error] %s\n", err.Error())
+		return
 	}
 
-	parser := network.NewBidParser(&req, data)
-	err, bids := parser.Parse()
-	chk(err)
+	requests[req.GetSeqId()] = req
+	fmt.Printf("%s %+v\n", network.GetCommandName(req.GetCmd()), req)
+	switch r := req.(type) {
+	case *network.BidReq:
+		for _, stock := range r.Stocks {
+			fmt.Printf("  %+v\n", stock)
+		}
+	case *network.InfoExReq:
+		for _, stock := range r.Stocks {
+			fmt.Printf("  %+v\n", stock)
+		}
+	case *network.FinanceReq:
+		for _, stock := range r.Stocks {
+			fmt.Printf("  %+v\n", stock)
+		}
+	}
+}
+
+func parseResp(data []byte) {
+	parser := network.NewRespParser(data)
+	err, seqId := parser.GetSeqId()
+	if err != nil {
+		fmt.Printf("[Error] %s\n", err.Error())
+		return
+	}
+	_, cmd := parser.GetCmd()
+
+	req, ok := requests[seqId]
+	if !ok || req.GetCmd() != cmd {
+		req = &network.Header{
+			Cmd: cmd,
+			SeqId: seqId,
+		}
+	}
 
-	for _, bid := range bids {
-		fmt.Printf("%+v\n", bid)
+	command := network.GetCommand(req.GetCmd())
+	if command == nil {
+		parser.Parse()
+		if parser.Err() != nil {
+			fmt.Printf("[Error] %s\n", parser.Err().Error())
+		} else {
+			fmt.Printf("%s %s\n", network.GetCommandName(req.GetCmd()), hex.EncodeToString(parser.Data))
+		}
+		return
+	}
+
+	// 这两个命令的响应需要请求中的参数才能解析
+	if _, ok := req.(*network.Header); ok && (req.GetCmd() == network.CMD_HIS_TRANS || req.GetCmd() == network.CMD_PERIOD_DATA) {
+		fmt.Printf("[Error] %s response without request\n", command.Name)
+		return
+	}
+
+	fmt.Println(command.Name)
+	err, result := command.DecodeResp(req, data)
+	if err != nil {
+		fmt.Printf("[Error] %s\n", err.Error())
+		return
+	}
+
+	switch r := result.(type) {
+	case map[string]*network.Bid:
+		for _, bid := range r {
+			fmt.Printf("%+v\n", bid)
+		}
+	case map[string]*network.Finance:
+		for code, finance := range r {
+			fmt.Printf("%s %+v\n", code, finance)
+		}
+	case map[string][]*network.InfoExEvent:
+		// 除权除息输出所有类别
+		for code, events := range r {
+			for _, event := range events {
+				fmt.Printf("%s %s %+v\n", code, network.GetXdxrCategoryName(event.Category), event)
+			}
+		}
+	default:
+		fmt.Printf("%+v\n", result)
+	}
+}
+
+func parseHex(text string) {
+	data, err := hex.DecodeString(text)
+	if err != nil {
+		fmt.Printf("[Error] %s\n", err.Error())
+		return
+	}
+
+	if bytes.HasPrefix(data, network.RESP_MAGIC) {
+		parseResp(data)
+	} else {
+		parseReq(data)
+	}
+}
+
+// parseCapture parses one line written by network.Recorder
+func parseCapture(text string) {
+	var capture network.Capture
+	if err := json.Unmarshal([]byte(text), &capture); err != nil {
+		fmt.Printf("[Error] %s\n", err.Error())
+		return
+	}
+
+	parseHex(capture.Req)
+	if capture.Resp != "" {
+		parseHex(capture.Resp)
+	}
+	if capture.Error != "" {
+		fmt.Printf("[Error] %s\n", capture.Error)
 	}
 }
 
@@ -41,19 +147,25 @@ func parseLog(filePath string) {
 	defer f.Close()
 
 	scaner := bufio.NewScanner(f)
+	scaner.Buffer(nil, 4 * 1024 * 1024)
 	for scaner.Scan() {
 		line := strings.TrimSpace(scaner.Text())
 		if line == "" {
 			continue
 		}
 		fmt.Println("")
-		fmt.Println(line)
-		parseBid(line)
+		if strings.HasPrefix(line, "{") {
+			parseCapture(line)
+		} else {
+			fmt.Println(line)
+			parseHex(line)
+		}
 	}
+	chk(scaner.Err())
 }
 
 func main() {
-	logFilePath := flag.String("log-file", "", "日志文件路径")
+	logFilePath := flag.String("log-file", "", "日志文件路径，每行一个请求或响应的hex，或者Recorder记录的json")
 	flag.Parse()
 
 	if *logFilePath == "" {
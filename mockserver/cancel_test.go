@@ -0,0 +1,143 @@
+package mockserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"github.com/stephenlyu/TdxProtocol/network"
+	"github.com/stephenlyu/tds/entity"
+)
+
+const promptReturn = 300 * time.Millisecond
+
+func checkPrompt(t *testing.T, start time.Time, expected time.Duration) {
+	if elapsed := time.Since(start); elapsed > expected + promptReturn {
+		t.Fatalf("expect return in %v, took %v", expected, elapsed)
+	}
+}
+
+func delayCmd(cmd uint16, delay time.Duration) func(req network.Request) time.Duration {
+	return func(req network.Request) time.Duration {
+		if req.GetCmd() == cmd {
+			return delay
+		}
+		return 0
+	}
+}
+
+func TestCancelPoolGet(t *testing.T) {
+	ds := createDataSource()
+	server, api := startServerWithOptions(t, ds, func(options *network.Options) {
+		options.MaxCap = 1
+		options.MaxConns = 1
+	})
+	defer server.Close()
+	defer api.Cleanup()
+
+	// 唯一的连接被占用
+	server.SetDelay(delayBid(time.Second))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		getBid(t, api)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	securities := []*entity.Security{entity.ParseSecurityUnsafe("000001.SZ")}
+	ctx, cancel := context.WithTimeout(context.Background(), 100 * time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err, _ := api.GetBidContext(ctx, securities)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect deadline exceeded, got %v", err)
+	}
+	checkPrompt(t, start, 100 * time.Millisecond)
+
+	<-done
+	checkStats(t, api, network.PoolStats{Idle: 1, Created: 1})
+}
+
+func TestCancelRead(t *testing.T) {
+	ds := createDataSource()
+	server, api := startServerWithOptions(t, ds, func(options *network.Options) {})
+	defer server.Close()
+	defer api.Cleanup()
+
+	server.SetDelay(delayBid(time.Second))
+	securities := []*entity.Security{entity.ParseSecurityUnsafe("000001.SZ")}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100 * time.Millisecond, cancel)
+	start := time.Now()
+	err, _ := api.GetBidContext(ctx, securities)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expect canceled, got %v", err)
+	}
+	checkPrompt(t, start, 100 * time.Millisecond)
+
+	// 读了一半的连接不能放回连接池
+	checkStats(t, api, network.PoolStats{Created: 1, Discarded: 1})
+
+	server.SetDelay(nil)
+	getBid(t, api)
+	checkStats(t, api, network.PoolStats{Idle: 1, Created: 2, Discarded: 1})
+}
+
+// startRetryServer makes every request of cmd time out, so the bizapi keeps retrying.
+func startRetryServer(t *testing.T, cmd uint16) (*Server, *network.BizApi) {
+	server := NewServer(createDataSource())
+	chk(t, server.Start("127.0.0.1:0"))
+	server.SetDelay(delayCmd(cmd, time.Second))
+
+	options := network.DefaultOptions()
+	options.Hosts = []string{server.Addr()}
+	options.InitialCap = 1
+	options.MaxCap = 2
+	options.ReadTimeout = 50 * time.Millisecond
+	err, api := network.CreateBizApiWithOptions(options)
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	return server, api
+}
+
+func checkRetryStats(t *testing.T, api *network.BizApi) {
+	stats := api.PoolStats()
+	if stats.InUse != 0 || stats.Idle != 0 || stats.Discarded != stats.Created {
+		t.Fatalf("timed out connections should be discarded, stats %+v", stats)
+	}
+}
+
+func TestCancelDownloadFileRetry(t *testing.T) {
+	server, api := startRetryServer(t, network.CMD_GET_FILE_DATA)
+	defer server.Close()
+	defer api.Cleanup()
+
+	// 第一次超时后在重试等待中取消
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200 * time.Millisecond, cancel)
+	start := time.Now()
+	err := api.DownloadFileContext(ctx, "zhb.zip", t.TempDir())
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expect canceled, got %v", err)
+	}
+	checkPrompt(t, start, 200 * time.Millisecond)
+	checkRetryStats(t, api)
+}
+
+func TestCancelNamesDataRetry(t *testing.T) {
+	server, api := startRetryServer(t, network.CMD_NAMES)
+	defer server.Close()
+	defer api.Cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200 * time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err, _ := api.GetNamesDataContext(ctx, 0)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect deadline exceeded, got %v", err)
+	}
+	checkPrompt(t, start, 200 * time.Millisecond)
+	checkRetryStats(t, api)
+}
@@ -0,0 +1,140 @@
+package mockserver
+
+import (
+	"github.com/stephenlyu/TdxProtocol/network"
+	"github.com/stephenlyu/tds/entity"
+)
+
+// DataSource provides the data served by Server, security is the full code like "000001.SZ".
+// Methods return nil if there is no data.
+type DataSource interface {
+	GetBid(security string) *network.Bid
+	GetRecords(security string, period uint16) []entity.Record				// 按时间排序
+	GetIndexRecords(security string, period uint16) []network.IndexRecord	// 非nil时按指数K线返回
+	GetInfoEx(security string) []*network.InfoExItem
+	GetInfoExEvents(security string) []*network.InfoExEvent				// 非nil时代替GetInfoEx
+	GetFinance(security string) *network.Finance
+	GetInstantTransactions(security string) []network.Transaction
+	GetHistoryTransactions(security string, date uint32) []network.Transaction
+	GetMinuteTimePoints(security string) []network.MinuteTimePoint
+	GetHistoryMinuteTimePoints(security string, date uint32) []network.MinuteTimePoint
+	GetFile(fileName string) []byte
+	GetNames(block uint16) []byte											// 每条记录29字节
+}
+
+// MemDataSource is an in-memory DataSource, fill the maps before serving.
+type MemDataSource struct {
+	Bids map[string]*network.Bid
+	Records map[string]map[uint16][]entity.Record
+	IndexRecords map[string]map[uint16][]network.IndexRecord
+	InfoEx map[string][]*network.InfoExItem
+	InfoExEvents map[string][]*network.InfoExEvent
+	Finances map[string]*network.Finance
+	InstantTrans map[string][]network.Transaction
+	HisTrans map[string]map[uint32][]network.Transaction
+	MinuteTime map[string][]network.MinuteTimePoint
+	HisMinuteTime map[string]map[uint32][]network.MinuteTimePoint
+	Files map[string][]byte
+	Names map[uint16][]byte
+}
+
+func NewMemDataSource() *MemDataSource {
+	return &MemDataSource{
+		Bids: map[string]*network.Bid{},
+		Records: map[string]map[uint16][]entity.Record{},
+		IndexRecords: map[string]map[uint16][]network.IndexRecord{},
+		InfoEx: map[string][]*network.InfoExItem{},
+		InfoExEvents: map[string][]*network.InfoExEvent{},
+		Finances: map[string]*network.Finance{},
+		InstantTrans: map[string][]network.Transaction{},
+		HisTrans: map[string]map[uint32][]network.Transaction{},
+		MinuteTime: map[string][]network.MinuteTimePoint{},
+		HisMinuteTime: map[string]map[uint32][]network.MinuteTimePoint{},
+		Files: map[string][]byte{},
+		Names: map[uint16][]byte{},
+	}
+}
+
+func (this *MemDataSource) SetRecords(security string, period uint16, records []entity.Record) {
+	m, ok := this.Records[security]
+	if !ok {
+		m = map[uint16][]entity.Record{}
+		this.Records[security] = m
+	}
+	m[period] = records
+}
+
+func (this *MemDataSource) SetIndexRecords(security string, period uint16, records []network.IndexRecord) {
+	m, ok := this.IndexRecords[security]
+	if !ok {
+		m = map[uint16][]network.IndexRecord{}
+		this.IndexRecords[security] = m
+	}
+	m[period] = records
+}
+
+func (this *MemDataSource) SetHistoryTransactions(security string, date uint32, transactions []network.Transaction) {
+	m, ok := this.HisTrans[security]
+	if !ok {
+		m = map[uint32][]network.Transaction{}
+		this.HisTrans[security] = m
+	}
+	m[date] = transactions
+}
+
+func (this *MemDataSource) SetHistoryMinuteTimePoints(security string, date uint32, points []network.MinuteTimePoint) {
+	m, ok := this.HisMinuteTime[security]
+	if !ok {
+		m = map[uint32][]network.MinuteTimePoint{}
+		this.HisMinuteTime[security] = m
+	}
+	m[date] = points
+}
+
+func (this *MemDataSource) GetBid(security string) *network.Bid {
+	return this.Bids[security]
+}
+
+func (this *MemDataSource) GetRecords(security string, period uint16) []entity.Record {
+	return this.Records[security][period]
+}
+
+func (this *MemDataSource) GetIndexRecords(security string, period uint16) []network.IndexRecord {
+	return this.IndexRecords[security][period]
+}
+
+func (this *MemDataSource) GetInfoEx(security string) []*network.InfoExItem {
+	return this.InfoEx[security]
+}
+
+func (this *MemDataSource) GetInfoExEvents(security string) []*network.InfoExEvent {
+	return this.InfoExEvents[security]
+}
+
+func (this *MemDataSource) GetFinance(security string) *network.Finance {
+	return this.Finances[security]
+}
+
+func (this *MemDataSource) GetInstantTransactions(security string) []network.Transaction {
+	return this.InstantTrans[security]
+}
+
+func (this *MemDataSource) GetHistoryTransactions(security string, date uint32) []network.Transaction {
+	return this.HisTrans[security][date]
+}
+
+func (this *MemDataSource) GetMinuteTimePoints(security string) []network.MinuteTimePoint {
+	return this.MinuteTime[security]
+}
+
+func (this *MemDataSource) GetHistoryMinuteTimePoints(security string, date uint32) []network.MinuteTimePoint {
+	return this.HisMinuteTime[security][date]
+}
+
+func (this *MemDataSource) GetFile(fileName string) []byte {
+	return this.Files[fileName]
+}
+
+func (this *MemDataSource) GetNames(block uint16) []byte {
+	return this.Names[block]
+}
@@ -0,0 +1,84 @@
+package mockserver
+
+import (
+	"net"
+	"testing"
+	"time"
+	"github.com/stephenlyu/TdxProtocol/network"
+	"github.com/stephenlyu/tds/entity"
+)
+
+// refusingAddr returns a local address nobody listens on
+func refusingAddr(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	chk(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+	return addr
+}
+
+func TestRankHosts(t *testing.T) {
+	server := NewServer(createDataSource())
+	chk(t, server.Start("127.0.0.1:0"))
+	defer server.Close()
+
+	refusing := refusingAddr(t)
+	stats := network.RankHosts([]string{refusing, server.Addr()}, time.Second)
+	if len(stats) != 2 || stats[0].Host != server.Addr() || stats[0].Err != nil || stats[0].Latency <= 0 {
+		t.Fatalf("reachable host should come first: %+v", stats)
+	}
+	if stats[1].Host != refusing || stats[1].Err == nil {
+		t.Fatalf("refusing host should fail: %+v", stats[1])
+	}
+}
+
+func TestFailover(t *testing.T) {
+	ds := createDataSource()
+	servers := map[string]*Server{}
+	for i := 0; i < 2; i++ {
+		server := NewServer(ds)
+		chk(t, server.Start("127.0.0.1:0"))
+		defer server.Close()
+		servers[server.Addr()] = server
+	}
+
+	hosts := []string{refusingAddr(t)}
+	for addr := range servers {
+		hosts = append(hosts, addr)
+	}
+
+	options := network.DefaultOptions()
+	options.Hosts = hosts
+	options.InitialCap = 1
+	options.MaxCap = 2
+	options.ReadTimeout = time.Second
+	err, api := network.CreateAPIWithOptions(options)
+	chk(t, err)
+	defer api.Cleanup()
+
+	active := api.CurrentHost()
+	if servers[active] == nil {
+		t.Fatalf("should not start on the refusing host %s", active)
+	}
+
+	security := entity.ParseSecurityUnsafe("000001.SZ")
+	for i := 0; i < 10; i++ {
+		if i == 3 {
+			// 停止当前服务器，请求切换到另一个服务器继续
+			servers[active].Close()
+		}
+
+		err, bids := api.GetBid([]*entity.Security{security})
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		if bids["000001.SZ"] == nil {
+			t.Fatalf("request %d: no bid", i)
+		}
+	}
+
+	current := api.CurrentHost()
+	if current == active || servers[current] == nil {
+		t.Fatalf("expect failover to the other server, current: %s", current)
+	}
+}
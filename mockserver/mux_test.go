@@ -0,0 +1,196 @@
+package mockserver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"github.com/stephenlyu/TdxProtocol/network"
+	"github.com/stephenlyu/tds/entity"
+)
+
+// 按成交请求的Offset延迟响应，其它请求立即响应
+func delayByOffset(delays map[uint16]time.Duration) func(req network.Request) time.Duration {
+	return func(req network.Request) time.Duration {
+		if r, ok := req.(*network.InstantTransReq); ok {
+			return delays[r.Offset]
+		}
+		return 0
+	}
+}
+
+func startMuxServer(t *testing.T, delay func(req network.Request) time.Duration, readTimeout time.Duration) (*Server, *network.API) {
+	server := NewServer(createDataSource())
+	server.SetDelay(delay)
+	chk(t, server.Start("127.0.0.1:0"))
+
+	options := network.DefaultOptions()
+	options.Hosts = []string{server.Addr()}
+	options.InitialCap = 0
+	options.MuxConns = 1
+	options.ReadTimeout = readTimeout
+	err, api := network.CreateAPIWithOptions(options)
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	api.SetMultiplexEnabled(true)
+
+	// 建立多路复用连接，并发请求同时发现没有连接时都会拨号
+	if err := api.HeartBeat(); err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	return server, api
+}
+
+type transResult struct {
+	err error
+	transactions []network.Transaction
+	elapsed time.Duration
+}
+
+// getTransAsync requests the transaction at offset from the latest one
+func getTransAsync(ctx context.Context, api *network.API, offset uint16) <-chan transResult {
+	ch := make(chan transResult, 1)
+	go func() {
+		start := time.Now()
+		err, transactions := api.GetInstantTransactionContext(ctx, entity.ParseSecurityUnsafe("000001.SZ"), offset, 1)
+		ch <- transResult{err, transactions, time.Since(start)}
+	}()
+	return ch
+}
+
+func checkTrans(t *testing.T, ds *MemDataSource, offset uint16, r transResult) {
+	if r.err != nil {
+		t.Fatalf("offset %d: %v", offset, r.err)
+	}
+	all := ds.InstantTrans["000001.SZ"]
+	if len(r.transactions) != 1 || r.transactions[0] != all[len(all) - 1 - int(offset)] {
+		t.Fatalf("offset %d: bad transactions %+v", offset, r.transactions)
+	}
+}
+
+func TestMuxOutOfOrder(t *testing.T) {
+	ds := createDataSource()
+	server, api := startMuxServer(t, delayByOffset(map[uint16]time.Duration{
+		0: 300 * time.Millisecond,
+		1: 200 * time.Millisecond,
+		2: 100 * time.Millisecond,
+	}), time.Second)
+	defer server.Close()
+	defer api.Cleanup()
+
+	chs := []<-chan transResult{}
+	for offset := uint16(0); offset < 3; offset++ {
+		chs = append(chs, getTransAsync(context.Background(), api, offset))
+	}
+	for offset, ch := range chs {
+		r := <-ch
+		checkTrans(t, ds, uint16(offset), r)
+		if r.elapsed > 600 * time.Millisecond {
+			t.Fatalf("offset %d: requests should be pipelined, took %v", offset, r.elapsed)
+		}
+	}
+
+	if n := server.AcceptedCount(); n != 1 {
+		t.Fatalf("expect 1 connection, got %d", n)
+	}
+}
+
+func TestMuxTimeout(t *testing.T) {
+	ds := createDataSource()
+	server, api := startMuxServer(t, delayByOffset(map[uint16]time.Duration{
+		0: 2 * time.Second,
+		1: 50 * time.Millisecond,
+		2: 400 * time.Millisecond,
+	}), 300 * time.Millisecond)
+	defer server.Close()
+	defer api.Cleanup()
+
+	slow := getTransAsync(context.Background(), api, 0)
+	time.Sleep(20 * time.Millisecond)
+	fast := getTransAsync(context.Background(), api, 1)
+
+	checkTrans(t, ds, 1, <-fast)
+	r := <-slow
+	if r.err == nil || !network.IsNetworkError(r.err) {
+		t.Fatalf("expect timeout, got %v", r.err)
+	}
+
+	// 超时只影响自己的请求，连接仍然可用
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkTrans(t, ds, 1, <-getTransAsync(context.Background(), api, 1))
+		}()
+	}
+	wg.Wait()
+
+	if n := server.AcceptedCount(); n != 1 {
+		t.Fatalf("timeout should not close the connection, %d connections", n)
+	}
+}
+
+// 服务器收到请求但不再响应，连接半开，超时后要重新连接
+func TestMuxHalfOpen(t *testing.T) {
+	ds := createDataSource()
+	var hang int32
+	server, api := startMuxServer(t, func(req network.Request) time.Duration {
+		if atomic.LoadInt32(&hang) == 1 {
+			return time.Hour
+		}
+		return 0
+	}, 300 * time.Millisecond)
+	defer server.Close()
+	defer api.Cleanup()
+
+	atomic.StoreInt32(&hang, 1)
+	r := <-getTransAsync(context.Background(), api, 1)
+	if r.err == nil || !network.IsNetworkError(r.err) {
+		t.Fatalf("expect timeout, got %v", r.err)
+	}
+
+	atomic.StoreInt32(&hang, 0)
+	checkTrans(t, ds, 1, <-getTransAsync(context.Background(), api, 1))
+
+	if n := server.AcceptedCount(); n != 2 {
+		t.Fatalf("half open connection should be replaced, %d connections", n)
+	}
+}
+
+func TestMuxCancel(t *testing.T) {
+	ds := createDataSource()
+	server, api := startMuxServer(t, delayByOffset(map[uint16]time.Duration{
+		0: 2 * time.Second,
+		2: 300 * time.Millisecond,
+	}), 5 * time.Second)
+	defer server.Close()
+	defer api.Cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := getTransAsync(ctx, api, 0)
+	other := getTransAsync(context.Background(), api, 2)
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	r := <-cancelled
+	if !errors.Is(r.err, context.Canceled) {
+		t.Fatalf("expect context.Canceled, got %v", r.err)
+	}
+	if r.elapsed > time.Second {
+		t.Fatalf("cancel should return promptly, took %v", r.elapsed)
+	}
+
+	checkTrans(t, ds, 2, <-other)
+	checkTrans(t, ds, 1, <-getTransAsync(context.Background(), api, 1))
+
+	if n := server.AcceptedCount(); n != 1 {
+		t.Fatalf("cancel should not close the connection, %d connections", n)
+	}
+}
@@ -0,0 +1,196 @@
+package mockserver
+
+import (
+	"testing"
+	"reflect"
+	"sync"
+	"time"
+	"github.com/stephenlyu/TdxProtocol/network"
+	"github.com/stephenlyu/tds/entity"
+)
+
+func startServerWithOptions(t *testing.T, ds DataSource, setup func(options *network.Options)) (*Server, *network.API) {
+	server := NewServer(ds)
+	chk(t, server.Start("127.0.0.1:0"))
+
+	options := network.DefaultOptions()
+	options.Hosts = []string{server.Addr()}
+	options.InitialCap = 1
+	options.MaxCap = 2
+	setup(options)
+	err, api := network.CreateAPIWithOptions(options)
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	return server, api
+}
+
+// delayBid delays bid requests only, so handshakes are not delayed.
+func delayBid(delay time.Duration) func(req network.Request) time.Duration {
+	return func(req network.Request) time.Duration {
+		if req.GetCmd() == network.CMD_BID {
+			return delay
+		}
+		return 0
+	}
+}
+
+// getBid is safe to call in other goroutines.
+func getBid(t *testing.T, api *network.API) {
+	err, bids := api.GetBid([]*entity.Security{entity.ParseSecurityUnsafe("000001.SZ")})
+	if err != nil || len(bids) != 1 {
+		t.Errorf("bad bids %+v, error: %v", bids, err)
+	}
+}
+
+func checkStats(t *testing.T, api *network.API, expected network.PoolStats) {
+	if stats := api.PoolStats(); stats != expected {
+		t.Fatalf("expect stats %+v, got %+v", expected, stats)
+	}
+}
+
+func TestKeepAliveEvictsDeadConn(t *testing.T) {
+	ds := createDataSource()
+	server, api := startServer(t, ds, false)
+	defer server.Close()
+	defer api.Cleanup()
+
+	// 服务端关闭空闲连接
+	server.CloseConns()
+
+	api.SetKeepAlive(20 * time.Millisecond)
+	deadline := time.Now().Add(2 * time.Second)
+	for api.PoolStats().Discarded == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("dead connection is not evicted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 连接池为空时保活不能新建连接
+	time.Sleep(100 * time.Millisecond)
+	api.SetKeepAlive(0)
+	stats := api.PoolStats()
+	if stats.Idle != 0 || stats.Discarded != 1 {
+		t.Fatalf("bad stats %+v", stats)
+	}
+	if server.AcceptedCount() != 1 {
+		t.Fatalf("keep alive dialed, accepted %d", server.AcceptedCount())
+	}
+
+	err, bids := api.GetBid([]*entity.Security{entity.ParseSecurityUnsafe("000001.SZ")})
+	chk(t, err)
+	if !reflect.DeepEqual(bids["000001.SZ"], ds.Bids["000001.SZ"]) {
+		t.Fatalf("bad bids %+v", bids)
+	}
+	if server.AcceptedCount() != 2 {
+		t.Fatalf("expect a new connection, accepted %d", server.AcceptedCount())
+	}
+}
+
+func TestPoolStats(t *testing.T) {
+	ds := createDataSource()
+	server, api := startServerWithOptions(t, ds, func(options *network.Options) {})
+	defer server.Close()
+	defer api.Cleanup()
+
+	checkStats(t, api, network.PoolStats{Idle: 1, Created: 1})
+	getBid(t, api)
+	checkStats(t, api, network.PoolStats{Idle: 1, Created: 1})
+
+	// 3个并发请求新建2个连接, 归还时多出的一个被关闭
+	server.SetDelay(delayBid(100 * time.Millisecond))
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			getBid(t, api)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	checkStats(t, api, network.PoolStats{InUse: 3, Created: 3})
+	wg.Wait()
+	checkStats(t, api, network.PoolStats{Idle: 2, Created: 3})
+
+	// 多路复用的连接不计入
+	server.SetDelay(nil)
+	api.SetMultiplexEnabled(true)
+	getBid(t, api)
+	api.SetMultiplexEnabled(false)
+	checkStats(t, api, network.PoolStats{Idle: 2, Created: 3})
+
+	// 出错的连接被丢弃
+	server.CloseConns()
+	err, _ := api.GetBid([]*entity.Security{entity.ParseSecurityUnsafe("000001.SZ")})
+	if err == nil {
+		t.Fatal("expect error on closed connection")
+	}
+	checkStats(t, api, network.PoolStats{Idle: 1, Created: 3, Discarded: 1})
+}
+
+func TestPoolMaxConns(t *testing.T) {
+	ds := createDataSource()
+	server, api := startServerWithOptions(t, ds, func(options *network.Options) {
+		options.MaxConns = 2
+	})
+	defer server.Close()
+	defer api.Cleanup()
+
+	server.SetDelay(delayBid(100 * time.Millisecond))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			getBid(t, api)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	checkStats(t, api, network.PoolStats{InUse: 2, Created: 2})
+	wg.Wait()
+
+	checkStats(t, api, network.PoolStats{Idle: 2, Created: 2})
+	if server.AcceptedCount() != 2 {
+		t.Fatalf("expect 2 connections, accepted %d", server.AcceptedCount())
+	}
+}
+
+func TestPoolIdleTimeout(t *testing.T) {
+	ds := createDataSource()
+	server, api := startServerWithOptions(t, ds, func(options *network.Options) {
+		options.IdleTimeout = 50 * time.Millisecond
+	})
+	defer server.Close()
+	defer api.Cleanup()
+
+	getBid(t, api)
+	checkStats(t, api, network.PoolStats{Idle: 1, Created: 1})
+
+	time.Sleep(100 * time.Millisecond)
+	getBid(t, api)
+	checkStats(t, api, network.PoolStats{Idle: 1, Created: 2, Expired: 1})
+	if server.AcceptedCount() != 2 {
+		t.Fatalf("expect 2 connections, accepted %d", server.AcceptedCount())
+	}
+}
+
+func TestPoolMaxLifetime(t *testing.T) {
+	ds := createDataSource()
+	server, api := startServerWithOptions(t, ds, func(options *network.Options) {
+		options.MaxLifetime = 100 * time.Millisecond
+	})
+	defer server.Close()
+	defer api.Cleanup()
+
+	// 连接一直在用也会过期
+	for i := 0; i < 5; i++ {
+		getBid(t, api)
+		time.Sleep(30 * time.Millisecond)
+	}
+	checkStats(t, api, network.PoolStats{Idle: 1, Created: 2, Expired: 1})
+	if server.AcceptedCount() != 2 {
+		t.Fatalf("expect 2 connections, accepted %d", server.AcceptedCount())
+	}
+}
@@ -0,0 +1,294 @@
+// Package mockserver is a local quote server speaking the TDX wire protocol, for testing API and BizApi.
+package mockserver
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+	"github.com/z-ray/log"
+	"github.com/stephenlyu/TdxProtocol/network"
+)
+
+const (
+	NAMES_PAGE_SIZE = 1000
+	NAMES_RECORD_LEN = 29
+)
+
+type Server struct {
+	dataSource DataSource
+	compress bool
+	delay func(req network.Request) time.Duration
+
+	closing chan struct{}
+	closeOnce sync.Once
+	accepted int
+
+	listener net.Listener
+
+	lock sync.Mutex
+	conns map[net.Conn]struct{}
+	wg sync.WaitGroup
+}
+
+func NewServer(dataSource DataSource) *Server {
+	return &Server{
+		dataSource: dataSource,
+		closing: make(chan struct{}),
+		conns: map[net.Conn]struct{}{},
+	}
+}
+
+// SetCompress makes the server zlib compress responses, call it before Start.
+func (this *Server) SetCompress(compress bool) {
+	this.compress = compress
+}
+
+// SetDelay makes the server handle every request in its own goroutine after the returned delay,
+// so responses on one connection may be out of order. nil handles requests in order again.
+func (this *Server) SetDelay(delay func(req network.Request) time.Duration) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.delay = delay
+}
+
+func (this *Server) getDelay() func(req network.Request) time.Duration {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.delay
+}
+
+// Start listens on addr, e.g. "127.0.0.1:0", and serves in background.
+func (this *Server) Start(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	this.listener = listener
+
+	this.wg.Add(1)
+	go func() {
+		defer this.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			this.lock.Lock()
+			this.conns[conn] = struct{}{}
+			this.accepted++
+			this.lock.Unlock()
+
+			this.wg.Add(1)
+			go this.serve(conn)
+		}
+	}()
+	return nil
+}
+
+func (this *Server) Addr() string {
+	return this.listener.Addr().String()
+}
+
+// AcceptedCount returns the count of connections accepted since Start.
+func (this *Server) AcceptedCount() int {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.accepted
+}
+
+func (this *Server) Close() error {
+	err := this.listener.Close()
+	this.closeOnce.Do(func() {
+		close(this.closing)
+	})
+
+	this.CloseConns()
+	this.wg.Wait()
+	return err
+}
+
+// CloseConns closes the accepted connections but keeps listening.
+func (this *Server) CloseConns() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	for conn := range this.conns {
+		conn.Close()
+	}
+}
+
+func (this *Server) serve(conn net.Conn) {
+	defer this.wg.Done()
+	defer func() {
+		conn.Close()
+		this.lock.Lock()
+		delete(this.conns, conn)
+		this.lock.Unlock()
+	}()
+
+	var writeLock sync.Mutex
+	for {
+		err, data := network.ReadReq(conn)
+		if err != nil {
+			if errors.Is(err, network.ErrBadFrame) {
+				log.Errorf("Server.serve - %v", err)
+			}
+			return
+		}
+
+		err, req := network.ParseReq(data)
+		if err != nil {
+			log.Errorf("Server.serve - bad request: %v", err)
+			return
+		}
+
+		delay := this.getDelay()
+		if delay == nil {
+			if !this.respond(conn, &writeLock, req) {
+				return
+			}
+			continue
+		}
+
+		this.wg.Add(1)
+		go func(req network.Request) {
+			defer this.wg.Done()
+			timer := time.NewTimer(delay(req))
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+				if !this.respond(conn, &writeLock, req) {
+					conn.Close()
+				}
+			case <-this.closing:
+			}
+		}(req)
+	}
+}
+
+// respond returns false if the connection should be closed.
+func (this *Server) respond(conn net.Conn, writeLock *sync.Mutex, req network.Request) bool {
+	err, resp := this.handle(req)
+	if err != nil {
+		log.Errorf("Server.serve - handle request fail, cmd: %s error: %v", network.GetCommandName(req.GetCmd()), err)
+		return false
+	}
+
+	writeLock.Lock()
+	defer writeLock.Unlock()
+	_, err = conn.Write(network.EncodeResp(req.GetSeqId(), req.GetCmd(), resp, this.compress))
+	return err == nil
+}
+
+// window returns the range of count items skipping offset items from the latest one.
+func window(n int, offset uint16, count uint16) (int, int) {
+	end := n - int(offset)
+	if end < 0 {
+		end = 0
+	}
+	start := end - int(count)
+	if start < 0 {
+		start = 0
+	}
+	return start, end
+}
+
+func fullCode(stock *network.StockDef) string {
+	return network.GetFullCode(stock.MarketLocation, stock.StockCode)
+}
+
+func (this *Server) handle(req network.Request) (error, []byte) {
+	switch r := req.(type) {
+	case *network.HeartBeatReq:
+		return nil, []byte{}
+	case *network.RawReq:
+		if r.Cmd == network.CMD_HANDSHAKE1 || r.Cmd == network.CMD_HANDSHAKE2 {
+			return nil, []byte{}
+		}
+		return network.ErrUnknownCmd, nil
+	case *network.BidReq:
+		bids := map[string]*network.Bid{}
+		for _, stock := range r.Stocks {
+			if bid := this.dataSource.GetBid(fullCode(stock)); bid != nil {
+				bids[fullCode(stock)] = bid
+			}
+		}
+		return nil, network.EncodeBidData(bids)
+	case *network.InfoExReq:
+		infoEx := map[string][]*network.InfoExEvent{}
+		for _, stock := range r.Stocks {
+			security := fullCode(stock)
+			if events := this.dataSource.GetInfoExEvents(security); events != nil {
+				infoEx[security] = events
+				continue
+			}
+			infoEx[security] = []*network.InfoExEvent{}
+			for _, item := range this.dataSource.GetInfoEx(security) {
+				infoEx[security] = append(infoEx[security], network.NewDividendEvent(item))
+			}
+		}
+		return nil, network.EncodeInfoExEvents(infoEx)
+	case *network.FinanceReq:
+		finances := map[string]*network.Finance{}
+		for _, stock := range r.Stocks {
+			if finance := this.dataSource.GetFinance(fullCode(stock)); finance != nil {
+				finances[fullCode(stock)] = finance
+			}
+		}
+		return nil, network.EncodeFinanceData(finances)
+	case *network.PeriodDataReq:
+		security := network.GetFullCode(byte(r.Location), r.StockCode)
+		if indexRecords := this.dataSource.GetIndexRecords(security, r.Period); indexRecords != nil {
+			start, end := window(len(indexRecords), r.Offset, r.Count)
+			return nil, network.EncodeIndexPeriodData(r.Period, indexRecords[start:end])
+		}
+		records := this.dataSource.GetRecords(security, r.Period)
+		start, end := window(len(records), r.Offset, r.Count)
+		return nil, network.EncodePeriodData(r.Period, records[start:end])
+	case *network.InstantTransReq:
+		transactions := this.dataSource.GetInstantTransactions(network.GetFullCode(byte(r.Location), r.StockCode))
+		start, end := window(len(transactions), r.Offset, r.Count)
+		return nil, network.EncodeInstantTransData(transactions[start:end])
+	case *network.HisTransReq:
+		transactions := this.dataSource.GetHistoryTransactions(network.GetFullCode(byte(r.Location), r.StockCode), r.Date)
+		start, end := window(len(transactions), r.Offset, r.Count)
+		return nil, network.EncodeHisTransData(transactions[start:end])
+	case *network.MinuteTimeReq:
+		points := this.dataSource.GetMinuteTimePoints(network.GetFullCode(byte(r.Location), r.StockCode))
+		return nil, network.EncodeMinuteTimeData(points)
+	case *network.HisMinuteTimeReq:
+		points := this.dataSource.GetHistoryMinuteTimePoints(network.GetFullCode(r.Location, r.StockCode), r.Date)
+		return nil, network.EncodeHisMinuteTimeData(points)
+	case *network.GetFileLenReq:
+		return nil, network.EncodeFileLenData(uint32(len(this.dataSource.GetFile(r.FileName))))
+	case *network.GetFileDataReq:
+		file := this.dataSource.GetFile(r.FileName)
+		offset := int(r.Offset)
+		if offset > len(file) {
+			offset = len(file)
+		}
+		end := offset + int(r.Length)
+		if end > len(file) {
+			end = len(file)
+		}
+		return nil, network.EncodeFileData(file[offset:end])
+	case *network.NamesLenReq:
+		names := this.dataSource.GetNames(r.Block)
+		return nil, network.EncodeNamesLenData(uint16(len(names) / NAMES_RECORD_LEN))
+	case *network.NamesReq:
+		names := this.dataSource.GetNames(r.Block)
+		total := len(names) / NAMES_RECORD_LEN
+		start := int(r.Offset)
+		if start > total {
+			start = total
+		}
+		end := start + NAMES_PAGE_SIZE
+		if end > total {
+			end = total
+		}
+		return nil, network.EncodeNamesData(uint16(end - start), names[start * NAMES_RECORD_LEN:end * NAMES_RECORD_LEN])
+	default:
+		return network.ErrUnknownCmd, nil
+	}
+}
@@ -0,0 +1,740 @@
+package mockserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"reflect"
+	"bytes"
+	"sync"
+	"sync/atomic"
+	"time"
+	"github.com/stephenlyu/TdxProtocol/network"
+	"github.com/stephenlyu/tds/entity"
+	"github.com/stephenlyu/tds/datasource/tdx"
+	"github.com/stephenlyu/tds/period"
+)
+
+func chk(t *testing.T, err error) {
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func createDataSource() *MemDataSource {
+	ds := NewMemDataSource()
+
+	ds.Bids["000001.SZ"] = &network.Bid{
+		StockCode: "000001.SZ", Close: 1050, YesterdayClose: 1000, Open: 1010, High: 1080, Low: 990,
+		Vol: 123456, Amount: 1.5e8, InnerVol: 60000, OuterVol: 63456,
+		BuyPrice1: 1049, SellPrice1: 1050, BuyVol1: 100, SellVol1: 200,
+		BuyPrice2: 1048, SellPrice2: 1051, BuyVol2: 300, SellVol2: 400,
+		BuyPrice3: 1047, SellPrice3: 1052, BuyVol3: 500, SellVol3: 600,
+		BuyPrice4: 1046, SellPrice4: 1053, BuyVol4: 700, SellVol4: 800,
+		BuyPrice5: 1045, SellPrice5: 1054, BuyVol5: 900, SellVol5: 1000,
+	}
+	ds.Bids["600000.SH"] = &network.Bid{StockCode: "600000.SH", Close: 800000, YesterdayClose: 810000, Open: 805000, High: 812000, Low: 799000, Vol: 1}
+
+	ds.InfoEx["000001.SZ"] = []*network.InfoExItem{
+		{Date: 20170711, Bonus: 0.158, DeliveredShares: 0, RationedSharePrice: 0, RationedShares: 0},
+		{Date: 20180712, Bonus: 0.136, DeliveredShares: 0.5, RationedSharePrice: 5.5, RationedShares: 0.25},
+		{Date: 20181031, DeliveredShares: 1},
+	}
+
+	ds.InfoExEvents["600000.SH"] = []*network.InfoExEvent{
+		{Date: 20180601, Category: network.XDXR_DIVIDEND, Bonus: 0.25},
+		{Date: 20180712, Category: network.XDXR_CAPITAL_CHANGE, FloatSharesBefore: 2810376.5, TotalSharesBefore: 2810376.5, FloatSharesAfter: 2935208, TotalSharesAfter: 2935208},
+		{Date: 20190101, Category: network.XDXR_SPLIT, SplitRatio: 2},
+	}
+
+	ds.Finances["600000.SH"] = &network.Finance{BShares: 1, TotalAssets: 6e12, NetProfit: 5.5e10, NetAdjustedAssets: 12.5}
+
+	ds.InstantTrans["000001.SZ"] = []network.Transaction{
+		{Minute: 570, Price: 1050, Volume: 10, Count: 1, BS: network.BS_BUY},
+		{Minute: 571, Price: 1049, Volume: 20, Count: 2, BS: network.BS_SELL},
+		{Minute: 572, Price: 1052, Volume: 30, Count: 3, BS: network.BS_BUY},
+	}
+	ds.SetHistoryTransactions("000001.SZ", 20181102, []network.Transaction{
+		{Date: 20181102, Minute: 570, Price: 1050, Volume: 10, Count: 1, BS: network.BS_BUY},
+		{Date: 20181102, Minute: 900, Price: 1020, Volume: 1000, Count: 25, BS: network.BS_SELL},
+	})
+
+	days := []entity.Record{}
+	for i, d := range []uint32{20181029, 20181030, 20181031, 20181101, 20181102} {
+		// 价格以厘为单位
+		price := 10000 + i * 100
+		days = append(days, entity.Record{
+			Date: tdxdatasource.DayDateToTimestamp(d),
+			Open: float64(price) / 1000, Close: float64(price + 50) / 1000, High: float64(price + 200) / 1000, Low: float64(price - 100) / 1000,
+			Volume: float64(1000 * (i + 1)), Amount: float64(10000 * (i + 1)),
+		})
+	}
+	ds.SetRecords("000001.SZ", network.PERIOD_DAY, days)
+	ds.SetRecords("000001.SZ", network.PERIOD_WEEK, days[:2])
+	ds.SetIndexRecords("000001.SH", network.PERIOD_DAY, []network.IndexRecord{
+		{Record: days[0], UpCount: 1000, DownCount: 500},
+		{Record: days[1], UpCount: 600, DownCount: 900},
+	})
+
+	minutes := []entity.Record{}
+	for i := 0; i < 3; i++ {
+		minutes = append(minutes, entity.Record{
+			Date: tdxdatasource.DayDateToTimestamp(20181102) + uint64(571 + i) * 60000,
+			Open: 9.87, Close: 9.8, High: 9.9, Low: 9.75, Volume: 100, Amount: 980,
+		})
+	}
+	ds.SetRecords("000001.SZ", network.PERIOD_MINUTE, minutes)
+
+	points := []network.MinuteTimePoint{}
+	for i := 0; i < 240; i++ {
+		points = append(points, network.MinuteTimePoint{Minute: network.GetTradingMinute(i), Price: uint32(980 + i % 7), Volume: uint32(i * 10)})
+	}
+	ds.MinuteTime["000001.SZ"] = points
+	ds.SetHistoryMinuteTimePoints("000001.SZ", 20181102, points[:3])
+
+	ds.Files["zhb.zip"] = bytes.Repeat([]byte("0123456789"), 7000)
+
+	infos := []*network.SecurityInfo{}
+	for i := 0; i < 1500; i++ {
+		infos = append(infos, &network.SecurityInfo{
+			Code: fmt.Sprintf("%06d.SZ", i + 1),
+			Name: fmt.Sprintf("S%d", i),
+			VolUnit: 100,
+			DecimalPoint: 2,
+			PreClose: float64(i) / 4,
+		})
+	}
+	ds.Names[network.MARKET_SZ] = network.EncodeSecurityList(infos)
+	ds.Names[network.MARKET_BJ] = network.EncodeSecurityList([]*network.SecurityInfo{
+		{Code: "430047.BJ", Name: "BJ", VolUnit: 100, DecimalPoint: 2, PreClose: 12.5},
+	})
+
+	return ds
+}
+
+func startServer(t *testing.T, ds DataSource, compress bool) (*Server, *network.API) {
+	server := NewServer(ds)
+	server.SetCompress(compress)
+	chk(t, server.Start("127.0.0.1:0"))
+
+	options := network.DefaultOptions()
+	options.Hosts = []string{server.Addr()}
+	options.InitialCap = 1
+	options.MaxCap = 2
+	err, api := network.CreateAPIWithOptions(options)
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	return server, api
+}
+
+func TestServer(t *testing.T) {
+	ds := createDataSource()
+
+	for _, compress := range []bool{false, true} {
+		server, api := startServer(t, ds, compress)
+
+		s1 := entity.ParseSecurityUnsafe("000001.SZ")
+		s2 := entity.ParseSecurityUnsafe("600000.SH")
+		s3 := entity.ParseSecurityUnsafe("000002.SZ")
+
+		err, bids := api.GetBid([]*entity.Security{s1, s2, s3})
+		chk(t, err)
+		if len(bids) != 2 || !reflect.DeepEqual(bids["000001.SZ"], ds.Bids["000001.SZ"]) || !reflect.DeepEqual(bids["600000.SH"], ds.Bids["600000.SH"]) {
+			t.Fatalf("bad bids: %+v", bids)
+		}
+
+		err, infoEx := api.GetInfoEx([]*entity.Security{s1, s2})
+		chk(t, err)
+		if len(infoEx["600000.SH"]) != 1 || !reflect.DeepEqual(infoEx["000001.SZ"], ds.InfoEx["000001.SZ"]) {
+			t.Fatalf("bad info ex: %+v", infoEx)
+		}
+		err, events := api.GetInfoExEvents([]*entity.Security{s2})
+		chk(t, err)
+		if !reflect.DeepEqual(events["600000.SH"], ds.InfoExEvents["600000.SH"]) {
+			t.Fatalf("bad info ex events: %+v", events)
+		}
+
+		err, finances := api.GetFinance([]*entity.Security{s1, s2})
+		chk(t, err)
+		if len(finances) != 1 || !reflect.DeepEqual(finances["600000.SH"], ds.Finances["600000.SH"]) {
+			t.Fatalf("bad finances: %+v", finances)
+		}
+
+		err, transactions := api.GetInstantTransaction(s1, 0, 2)
+		chk(t, err)
+		if !reflect.DeepEqual(transactions, ds.InstantTrans["000001.SZ"][1:]) {
+			t.Fatalf("bad transactions: %+v", transactions)
+		}
+
+		err, transactions = api.GetHistoryTransaction(s1, 20181102, 0, 10)
+		chk(t, err)
+		if !reflect.DeepEqual(transactions, ds.HisTrans["000001.SZ"][20181102]) {
+			t.Fatalf("bad history transactions: %+v", transactions)
+		}
+
+		err, records := api.GetDayData(s1, 1, 3)
+		chk(t, err)
+		if !reflect.DeepEqual(records, ds.Records["000001.SZ"][network.PERIOD_DAY][1:4]) {
+			t.Fatalf("bad day data: %+v", records)
+		}
+
+		err, records = api.GetMinuteData(s1, 0, 10)
+		chk(t, err)
+		if !reflect.DeepEqual(records, ds.Records["000001.SZ"][network.PERIOD_MINUTE]) {
+			t.Fatalf("bad minute data: %+v", records)
+		}
+
+		err, points := api.GetMinuteTimeData(s1)
+		chk(t, err)
+		if !reflect.DeepEqual(points, ds.MinuteTime["000001.SZ"]) || points[239].Minute != 15 * 60 {
+			t.Fatalf("bad minute time data: %+v", points)
+		}
+		err, points = api.GetHistoryMinuteTimeData(s1, 20181102)
+		chk(t, err)
+		if !reflect.DeepEqual(points, ds.HisMinuteTime["000001.SZ"][20181102]) {
+			t.Fatalf("bad history minute time data: %+v", points)
+		}
+
+		err, length := api.GetFileLength("zhb.zip")
+		chk(t, err)
+		err, n, data := api.GetFileData("zhb.zip", 30000, 30000)
+		chk(t, err)
+		if length != 70000 || n != 30000 || !bytes.Equal(data, ds.Files["zhb.zip"][30000:60000]) {
+			t.Fatalf("bad file data, length: %d n: %d", length, n)
+		}
+
+		err, count := api.GetNamesLength(0)
+		chk(t, err)
+		err, n1, names := api.GetNamesData(0, 1000)
+		chk(t, err)
+		if count != 1500 || n1 != 500 || len(names) != 500 * NAMES_RECORD_LEN {
+			t.Fatalf("bad names, count: %d n: %d", count, n1)
+		}
+
+		chk(t, api.HeartBeat())
+
+		api.Cleanup()
+		server.Close()
+	}
+}
+
+func TestBizApi(t *testing.T) {
+	ds := createDataSource()
+	server := NewServer(ds)
+	server.SetCompress(true)
+	chk(t, server.Start("127.0.0.1:0"))
+	defer server.Close()
+
+	err, api := network.CreateBizApiWithHosts([]string{server.Addr()})
+	chk(t, err)
+	defer api.Cleanup()
+
+	err, names := api.GetNamesData(0)
+	chk(t, err)
+	if !bytes.Equal(names, ds.Names[0]) {
+		t.Fatalf("bad names data, len: %d", len(names))
+	}
+
+	err, infos := api.GetAllSecurityList()
+	chk(t, err)
+	if len(infos) != 1501 || infos[1499].Code != "001500.SZ" || infos[1499].Name != "S1499" || infos[1499].PreClose != 374.75 || infos[1500].Code != "430047.BJ" {
+		t.Fatalf("bad security list, len: %d", len(infos))
+	}
+
+	api.SetWorkDir(t.TempDir())
+	err, infos = api.GetNames(network.MARKET_SZ)
+	chk(t, err)
+	if len(infos) != 1500 || infos[0].Pinyin != "S0" || infos[0].Block != network.BLOCK_SZ_A {
+		t.Fatalf("bad names, len: %d", len(infos))
+	}
+
+	err, records := api.GetLatestDayData(entity.ParseSecurityUnsafe("000001.SZ"), 10)
+	chk(t, err)
+	if !reflect.DeepEqual(records, ds.Records["000001.SZ"][network.PERIOD_DAY]) {
+		t.Fatalf("bad day data: %+v", records)
+	}
+
+	err, records = api.GetAdjustedPeriodData(entity.ParseSecurityUnsafe("000001.SZ"), period.PERIOD_D, 0, 10, network.ADJUST_BACKWARD)
+	chk(t, err)
+	if len(records) != 5 || records[1].Close != 10.15 || records[2].Close != 20.5 {
+		t.Fatalf("bad adjusted data: %+v", records)
+	}
+	// 后复权以上市首日为基准，与请求的范围无关
+	err, window := api.GetAdjustedPeriodData(entity.ParseSecurityUnsafe("000001.SZ"), period.PERIOD_D, 0, 2, network.ADJUST_BACKWARD)
+	chk(t, err)
+	if !reflect.DeepEqual(window, records[3:]) {
+		t.Fatalf("bad adjusted window: %+v", window)
+	}
+
+	err, indexRecords := api.GetIndexPeriodData(entity.ParseSecurityUnsafe("000001.SH"), period.PERIOD_D, 0, 10)
+	chk(t, err)
+	if !reflect.DeepEqual(indexRecords, ds.IndexRecords["000001.SH"][network.PERIOD_DAY]) {
+		t.Fatalf("bad index data: %+v", indexRecords)
+	}
+
+	_, week := period.PeriodFromString("W1")
+	err, records = api.GetLatestPeriodData(entity.ParseSecurityUnsafe("000001.SZ"), week, 0, 10)
+	chk(t, err)
+	if !reflect.DeepEqual(records, ds.Records["000001.SZ"][network.PERIOD_WEEK]) {
+		t.Fatalf("bad week data: %+v", records)
+	}
+
+	dir := t.TempDir()
+	chk(t, api.DownloadFile("zhb.zip", dir))
+}
+
+// 快照只取A股和指数，北交所的债券等不取
+func TestSnapshotSecurities(t *testing.T) {
+	ds := NewMemDataSource()
+	names := map[uint16][]string{
+		network.MARKET_SZ: {"000001.SZ", "200002.SZ", "399001.SZ"},
+		network.MARKET_SH: {"600000.SH", "900901.SH", "000001.SH"},
+		network.MARKET_BJ: {"430047.BJ", "920002.BJ", "899050.BJ", "810001.BJ"},
+	}
+	for market, codes := range names {
+		infos := []*network.SecurityInfo{}
+		for _, code := range codes {
+			infos = append(infos, &network.SecurityInfo{Code: code, Name: code, VolUnit: 100, DecimalPoint: 2})
+		}
+		ds.Names[market] = network.EncodeSecurityList(infos)
+	}
+
+	server := NewServer(ds)
+	chk(t, server.Start("127.0.0.1:0"))
+	defer server.Close()
+
+	err, api := network.CreateBizApiWithHosts([]string{server.Addr()})
+	chk(t, err)
+	defer api.Cleanup()
+
+	err, securities := api.GetSnapshotSecurities()
+	chk(t, err)
+	codes := map[string]bool{}
+	for _, security := range securities {
+		codes[security.String()] = true
+	}
+	expect := map[string]bool{"000001.SZ": true, "399001.SZ": true, "600000.SH": true, "000001.SH": true, "430047.BJ": true, "920002.BJ": true, "899050.BJ": true}
+	if !reflect.DeepEqual(codes, expect) {
+		t.Fatalf("bad securities: %v", codes)
+	}
+}
+
+func TestSnapshotEngine(t *testing.T) {
+	ds := createDataSource()
+	server := NewServer(ds)
+	chk(t, server.Start("127.0.0.1:0"))
+	defer server.Close()
+
+	err, api := network.CreateBizApiWithHosts([]string{server.Addr()})
+	chk(t, err)
+	defer api.Cleanup()
+
+	securities := []*entity.Security{entity.ParseSecurityUnsafe("600000.SH")}
+	for i := 0; i < 50; i++ {
+		securities = append(securities, entity.ParseSecurityUnsafe(fmt.Sprintf("%06d.SZ", i + 1)))
+	}
+
+	engine := network.NewSnapshotEngine(api, securities)
+	engine.SetConcurrency(3)
+	updates := engine.Subscribe(10)
+
+	chk(t, engine.Refresh(context.Background()))
+	snapshot := engine.Snapshot()
+	if len(snapshot.Bids) != 2 || !reflect.DeepEqual(snapshot.Bids["000001.SZ"], ds.Bids["000001.SZ"]) || snapshot.Time.IsZero() {
+		t.Fatalf("bad snapshot: %+v", snapshot)
+	}
+	update := <-updates
+	if len(update.Deltas) != 2 || update.Deltas[0].StockCode != "000001.SZ" || update.Deltas[0].Old != nil || len(update.Deltas[0].Fields) != 30 {
+		t.Fatalf("bad update: %+v", update)
+	}
+
+	bid := *ds.Bids["000001.SZ"]
+	bid.Close = 1051
+	bid.Vol++
+	ds.Bids["000001.SZ"] = &bid
+	chk(t, engine.Refresh(context.Background()))
+	update = <-updates
+	if len(update.Deltas) != 1 || !reflect.DeepEqual(update.Deltas[0].Fields, []string{"Close", "Vol"}) || update.Deltas[0].Old.Close != 1050 {
+		t.Fatalf("bad update: %+v", update.Deltas[0])
+	}
+	if engine.GetBid("000001.SZ").Close != 1051 || snapshot.Bids["000001.SZ"].Close != 1050 {
+		t.Fatal("snapshot should not be modified")
+	}
+
+	// 没有变化时不通知
+	chk(t, engine.Refresh(context.Background()))
+	select {
+	case update = <-updates:
+		t.Fatalf("unexpected update: %+v", update)
+	default:
+	}
+
+	engine.Unsubscribe(updates)
+	if _, ok := <-updates; ok {
+		t.Fatal("channel should be closed")
+	}
+}
+
+// 失败的分块保留旧行情和旧的更新时间
+func TestSnapshotPartialFailure(t *testing.T) {
+	ds := createDataSource()
+	ds.Bids["000021.SZ"] = &network.Bid{StockCode: "000021.SZ", Close: 2000, Vol: 1}
+
+	// 包含000021的请求超时
+	var failing int32
+	server := NewServer(ds)
+	server.SetDelay(func(req network.Request) time.Duration {
+		if r, ok := req.(*network.BidReq); ok && atomic.LoadInt32(&failing) == 1 {
+			for _, stock := range r.Stocks {
+				if stock.StockCode == "000021" {
+					return time.Second
+				}
+			}
+		}
+		return 0
+	})
+	chk(t, server.Start("127.0.0.1:0"))
+	defer server.Close()
+
+	options := network.DefaultOptions()
+	options.Hosts = []string{server.Addr()}
+	options.ReadTimeout = 200 * time.Millisecond
+	err, api := network.CreateBizApiWithOptions(options)
+	chk(t, err)
+	defer api.Cleanup()
+
+	// 每个分块20个代码，000021在第二个分块
+	securities := []*entity.Security{entity.ParseSecurityUnsafe("600000.SH")}
+	for i := 0; i < 50; i++ {
+		securities = append(securities, entity.ParseSecurityUnsafe(fmt.Sprintf("%06d.SZ", i + 1)))
+	}
+	engine := network.NewSnapshotEngine(api, securities)
+	chk(t, engine.Refresh(context.Background()))
+	first := engine.Snapshot()
+	if first.IsStale("000001.SZ") || first.IsStale("000021.SZ") || first.IsStale("000050.SZ") {
+		t.Fatalf("nothing should be stale: %+v", first.Updated)
+	}
+
+	atomic.StoreInt32(&failing, 1)
+	bid := *ds.Bids["000001.SZ"]
+	bid.Close = 1051
+	ds.Bids["000001.SZ"] = &bid
+
+	if err := engine.Refresh(context.Background()); err == nil {
+		t.Fatal("expect error of the failed chunk")
+	}
+	second := engine.Snapshot()
+	if !second.Time.After(first.Time) || second.Bids["000001.SZ"].Close != 1051 || second.IsStale("000001.SZ") || second.IsStale("000041.SZ") {
+		t.Fatalf("succeeded chunks should be updated: %+v", second.Updated)
+	}
+	if !second.IsStale("000021.SZ") || !second.IsStale("000020.SZ") || !second.Updated["000021.SZ"].Equal(first.Updated["000021.SZ"]) ||
+		second.Bids["000021.SZ"] != first.Bids["000021.SZ"] {
+		t.Fatalf("failed chunk should keep the old bids and update time: %+v", second.Updated)
+	}
+}
+
+// 全市场约5000个代码，目标是一秒内刷新完成
+func BenchmarkSnapshotRefresh(b *testing.B) {
+	ds := NewMemDataSource()
+	securities := []*entity.Security{}
+	for i := 0; i < 5000; i++ {
+		code := fmt.Sprintf("%06d.SZ", i + 1)
+		ds.Bids[code] = &network.Bid{StockCode: code, Close: uint32(1000 + i), YesterdayClose: 1000, Vol: uint32(i)}
+		securities = append(securities, entity.ParseSecurityUnsafe(code))
+	}
+
+	server := NewServer(ds)
+	if err := server.Start("127.0.0.1:0"); err != nil {
+		b.Fatal(err)
+	}
+	defer server.Close()
+
+	options := network.DefaultOptions()
+	options.Hosts = []string{server.Addr()}
+	options.MaxCap = 10
+	err, api := network.CreateBizApiWithOptions(options)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer api.Cleanup()
+
+	engine := network.NewSnapshotEngine(api, securities)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := engine.Refresh(context.Background()); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+
+	if len(engine.Snapshot().Bids) != len(securities) {
+		b.Fatalf("expect %d bids, got %d", len(securities), len(engine.Snapshot().Bids))
+	}
+	if perRefresh := b.Elapsed() / time.Duration(b.N); perRefresh > time.Second {
+		b.Errorf("full market refresh takes %v", perRefresh)
+	}
+}
+
+// lockedDataSource allows the test to change the data while the server is serving
+type lockedDataSource struct {
+	*MemDataSource
+	lock sync.Mutex
+}
+
+func (this *lockedDataSource) GetBid(security string) *network.Bid {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.MemDataSource.GetBid(security)
+}
+
+func (this *lockedDataSource) GetInstantTransactions(security string) []network.Transaction {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.MemDataSource.GetInstantTransactions(security)
+}
+
+func TestSubscribe(t *testing.T) {
+	ds := &lockedDataSource{MemDataSource: createDataSource()}
+	server := NewServer(ds)
+	chk(t, server.Start("127.0.0.1:0"))
+	defer server.Close()
+
+	err, api := network.CreateBizApiWithHosts([]string{server.Addr()})
+	chk(t, err)
+	defer api.Cleanup()
+
+	options := network.DefaultSubscribeOptions()
+	options.Interval = 10 * time.Millisecond
+	options.BufferSize = 0
+	options.TransPageSize = 2
+	s1 := entity.ParseSecurityUnsafe("000001.SZ")
+	subscription := api.SubscribeWithOptions(context.Background(), []*entity.Security{s1}, network.SUBSCRIBE_BID | network.SUBSCRIBE_TRANS, options)
+
+	update := <-subscription.C
+	if !reflect.DeepEqual(update.Bid, ds.Bids["000001.SZ"]) || !reflect.DeepEqual(update.Transactions, ds.InstantTrans["000001.SZ"][1:]) {
+		t.Fatalf("bad first update: %+v", update)
+	}
+
+	// 重复的成交及新成交
+	ds.lock.Lock()
+	trans := append([]network.Transaction{}, ds.InstantTrans["000001.SZ"]...)
+	trans = append(trans, trans[2], network.Transaction{Minute: 573, Price: 1053, Volume: 40, Count: 4, BS: network.BS_SELL})
+	ds.InstantTrans["000001.SZ"] = trans
+	ds.lock.Unlock()
+
+	update = <-subscription.C
+	if update.Bid != nil || !reflect.DeepEqual(update.Transactions, trans[3:]) {
+		t.Fatalf("bad update: %+v", update)
+	}
+
+	ds.lock.Lock()
+	bid := *ds.Bids["000001.SZ"]
+	bid.Close = 1053
+	ds.Bids["000001.SZ"] = &bid
+	ds.lock.Unlock()
+
+	update = <-subscription.C
+	if update.Bid == nil || update.Bid.Close != 1053 || len(update.Transactions) != 0 {
+		t.Fatalf("bad update: %+v", update)
+	}
+
+	subscription.Close()
+	for range subscription.C {
+	}
+}
+
+// 新成交超过MaxTransPages页时更新带TransTruncated，并通过OnError报告
+func TestSubscribeTransTruncated(t *testing.T) {
+	ds := &lockedDataSource{MemDataSource: createDataSource()}
+	server := NewServer(ds)
+	chk(t, server.Start("127.0.0.1:0"))
+	defer server.Close()
+
+	err, api := network.CreateBizApiWithHosts([]string{server.Addr()})
+	chk(t, err)
+	defer api.Cleanup()
+
+	errs := make(chan error, 10)
+	options := network.DefaultSubscribeOptions()
+	options.Interval = 10 * time.Millisecond
+	options.BufferSize = 0
+	options.TransPageSize = 2
+	options.MaxTransPages = 2
+	options.OnError = func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}
+	s1 := entity.ParseSecurityUnsafe("000001.SZ")
+	subscription := api.SubscribeWithOptions(context.Background(), []*entity.Security{s1}, network.SUBSCRIBE_TRANS, options)
+	defer func() {
+		subscription.Close()
+		for range subscription.C {
+		}
+	}()
+
+	update := <-subscription.C
+	if update.TransTruncated {
+		t.Fatalf("bad first update: %+v", update)
+	}
+
+	ds.lock.Lock()
+	trans := append([]network.Transaction{}, ds.InstantTrans["000001.SZ"]...)
+	for i := 0; i < 6; i++ {
+		trans = append(trans, network.Transaction{Minute: uint16(580 + i), Price: 1060, Volume: 10, Count: 1})
+	}
+	ds.InstantTrans["000001.SZ"] = trans
+	ds.lock.Unlock()
+
+	// 两页只取到最后4笔
+	update = <-subscription.C
+	if !update.TransTruncated || !reflect.DeepEqual(update.Transactions, trans[len(trans) - 4:]) {
+		t.Fatalf("bad update: %+v", update)
+	}
+	if err := <-errs; !errors.Is(err, network.ErrTransTruncated) {
+		t.Fatalf("expect ErrTransTruncated, got %v", err)
+	}
+}
+
+func TestGetAllTransactions(t *testing.T) {
+	ds := createDataSource()
+	transactions := []network.Transaction{}
+	for i := 0; i < 4500; i++ {
+		transactions = append(transactions, network.Transaction{Date: 20181101, Minute: uint16(570 + i / 20), Price: uint32(1000 + i % 7), Volume: uint32(i), Count: 1})
+	}
+	ds.SetHistoryTransactions("000001.SZ", 20181101, transactions)
+
+	server := NewServer(ds)
+	chk(t, server.Start("127.0.0.1:0"))
+	defer server.Close()
+
+	err, api := network.CreateBizApiWithHosts([]string{server.Addr()})
+	chk(t, err)
+	defer api.Cleanup()
+	api.SetWorkDir(t.TempDir())
+
+	s1 := entity.ParseSecurityUnsafe("000001.SZ")
+	err, result := api.GetAllTransactions(s1, 20181101)
+	chk(t, err)
+	if len(result) != 4500 || result[0].Volume != 0 || result[4499].Volume != 4499 || result[19].Seq != 19 || result[20].Seq != 0 {
+		t.Fatalf("bad transactions, len: %d", len(result))
+	}
+
+	err, result = api.GetAllTransactions(s1, 0)
+	chk(t, err)
+	if len(result) != 3 || result[0].Minute != 570 || result[2].Seq != 0 {
+		t.Fatalf("bad instant transactions: %+v", result)
+	}
+
+	s2 := entity.ParseSecurityUnsafe("600000.SH")
+	chk(t, api.DownloadTransactions([]*entity.Security{s1, s2}, []uint32{20181101, 20181102}))
+	err, result = api.LoadTransactions(s1, 20181102)
+	chk(t, err)
+	if len(result) != 2 || result[1].Minute != 900 {
+		t.Fatalf("bad saved transactions: %+v", result)
+	}
+	err, result = api.LoadTransactions(s2, 20181101)
+	chk(t, err)
+	if len(result) != 0 {
+		t.Fatalf("bad saved transactions: %+v", result)
+	}
+}
+
+// growingDataSource appends new transactions before serving the instant transactions for the calls in growAt
+type growingDataSource struct {
+	*MemDataSource
+	lock sync.Mutex
+	calls int
+	growAt map[int]int
+}
+
+func (this *growingDataSource) GetInstantTransactions(security string) []network.Transaction {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.calls++
+	transactions := this.MemDataSource.InstantTrans[security]
+	for i := 0; i < this.growAt[this.calls]; i++ {
+		transactions = append(transactions, network.Transaction{Minute: 899, Price: uint32(2000 + i), Volume: 1, Count: 1})
+	}
+	this.MemDataSource.InstantTrans[security] = transactions
+	return transactions
+}
+
+// 订阅翻页期间有新成交时, 已推送的成交不重复推送
+func TestSubscribeTransShift(t *testing.T) {
+	ds := &growingDataSource{MemDataSource: createDataSource(), growAt: map[int]int{3: 1}}
+	transactions := []network.Transaction{}
+	for i := 0; i < 6; i++ {
+		transactions = append(transactions, network.Transaction{Minute: uint16(569 + i / 4), Price: uint32(1000 + i), Volume: 10, Count: 1})
+	}
+	ds.InstantTrans["000001.SZ"] = transactions
+
+	server := NewServer(ds)
+	chk(t, server.Start("127.0.0.1:0"))
+	defer server.Close()
+
+	err, api := network.CreateBizApiWithHosts([]string{server.Addr()})
+	chk(t, err)
+	defer api.Cleanup()
+
+	options := network.DefaultSubscribeOptions()
+	options.Interval = 10 * time.Millisecond
+	options.BufferSize = 0
+	options.TransPageSize = 2
+	s1 := entity.ParseSecurityUnsafe("000001.SZ")
+	subscription := api.SubscribeWithOptions(context.Background(), []*entity.Security{s1}, network.SUBSCRIBE_TRANS, options)
+	defer func() {
+		subscription.Close()
+		for range subscription.C {
+		}
+	}()
+
+	update := <-subscription.C
+	if !reflect.DeepEqual(update.Transactions, transactions[4:]) {
+		t.Fatalf("bad first update: %+v", update)
+	}
+
+	// 第二次轮询翻页时新增一笔成交, 偏移错位的页不能产生重复
+	update = <-subscription.C
+	expected := []network.Transaction{{Minute: 899, Price: 2000, Volume: 1, Count: 1}}
+	if !reflect.DeepEqual(update.Transactions, expected) {
+		t.Fatalf("bad update: %+v", update.Transactions)
+	}
+}
+
+// 翻页期间有新成交时不重复，页边界上相同的成交不丢失
+func TestGetAllInstantTransactions(t *testing.T) {
+	transactions := []network.Transaction{}
+	for i := 0; i < 4500; i++ {
+		transactions = append(transactions, network.Transaction{Minute: uint16(570 + i / 20), Price: uint32(1000 + i % 7), Volume: uint32(i), Count: 1})
+	}
+	// 每页2000笔，页边界两侧是相同的成交
+	transactions[2499] = transactions[2500]
+	transactions[499] = transactions[500]
+
+	for _, growAt := range []map[int]int{{}, {2: 5}, {2: 5, 4: 3, 5: 1}} {
+		ds := &growingDataSource{MemDataSource: createDataSource(), growAt: growAt}
+		ds.InstantTrans["000001.SZ"] = append([]network.Transaction{}, transactions...)
+
+		server := NewServer(ds)
+		chk(t, server.Start("127.0.0.1:0"))
+		err, api := network.CreateBizApiWithHosts([]string{server.Addr()})
+		chk(t, err)
+
+		err, result := api.GetAllTransactions(entity.ParseSecurityUnsafe("000001.SZ"), 0)
+		api.Cleanup()
+		server.Close()
+		chk(t, err)
+
+		for i := range result {
+			result[i].Seq = 0
+		}
+		if !reflect.DeepEqual(result, transactions) {
+			t.Fatalf("grow at %v: bad transactions, len: %d", growAt, len(result))
+		}
+	}
+}
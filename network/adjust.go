@@ -0,0 +1,117 @@
+package network
+
+import (
+	"sort"
+	"github.com/stephenlyu/tds/datasource/tdx"
+	"github.com/stephenlyu/tds/entity"
+)
+
+// 复权方式
+const (
+	ADJUST_NONE = 0
+	ADJUST_FORWARD = 1			// 前复权，最新价格不变
+	ADJUST_BACKWARD = 2			// 后复权，上市首日的价格不变
+)
+
+// GetExRightPrice returns the 除权除息 reference price:
+// (前收盘 - 每股红利 + 每股配股数 * 配股价) / (1 + 每股送转股数 + 每股配股数)
+func GetExRightPrice(preClose float64, item *InfoExItem) float64 {
+	bonus := float64(item.Bonus)
+	deliveredShares := float64(item.DeliveredShares)
+	rationedShares := float64(item.RationedShares)
+	rationedSharePrice := float64(item.RationedSharePrice)
+	return (preClose - bonus + rationedShares * rationedSharePrice) / (1 + deliveredShares + rationedShares)
+}
+
+// AdjustFactor is the cumulative backward adjust factor of the records from Date on.
+type AdjustFactor struct {
+	Date uint32
+	Factor float64
+}
+
+// GetAdjustFactors returns the cumulative backward adjust factors, the factor before the first item is 1.
+// days are the daily records giving the previous close of the items, they should start from listing so
+// the backward adjusted prices are anchored at listing whatever records are adjusted. An item is applied
+// to the first day on or after its date, items without a previous close are ignored.
+func GetAdjustFactors(days []entity.Record, infoEx []*InfoExItem) []AdjustFactor {
+	result := []AdjustFactor{}
+	if len(days) == 0 {
+		return result
+	}
+
+	items := make([]*InfoExItem, len(infoEx))
+	copy(items, infoEx)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Date < items[j].Date
+	})
+
+	j := 0
+	firstDay := tdxdatasource.TimestampToDayDate(days[0].Date)
+	for j < len(items) && items[j].Date <= firstDay {
+		j++
+	}
+
+	factor := 1.0
+	for i := 1; i < len(days) && j < len(items); i++ {
+		day := tdxdatasource.TimestampToDayDate(days[i].Date)
+		preClose := days[i - 1].Close
+		changed := false
+		for ; j < len(items) && items[j].Date <= day; j++ {
+			exPrice := GetExRightPrice(preClose, items[j])
+			if preClose <= 0 || exPrice <= 0 {
+				continue
+			}
+			factor *= preClose / exPrice
+			preClose = exPrice
+			changed = true
+		}
+		if changed {
+			result = append(result, AdjustFactor{Date: day, Factor: factor})
+		}
+	}
+	return result
+}
+
+// getAdjustFactor returns the factor of the records on day
+func getAdjustFactor(factors []AdjustFactor, day uint32) float64 {
+	i := sort.Search(len(factors), func(i int) bool {
+		return factors[i].Date > day
+	})
+	if i == 0 {
+		return 1
+	}
+	return factors[i - 1].Factor
+}
+
+// AdjustRecords returns a new slice of adjusted records, volume and amount are not changed.
+// Forward adjusted prices of the latest day are not changed, backward adjusted prices of listing are not changed.
+func AdjustRecords(records []entity.Record, factors []AdjustFactor, adjustType int) (error, []entity.Record) {
+	result := make([]entity.Record, len(records))
+	copy(result, records)
+
+	switch adjustType {
+	case ADJUST_NONE:
+		return nil, result
+	case ADJUST_FORWARD, ADJUST_BACKWARD:
+	default:
+		return ErrBadAdjustType, nil
+	}
+
+	latest := 1.0
+	if len(factors) > 0 {
+		latest = factors[len(factors) - 1].Factor
+	}
+
+	for i := range result {
+		factor := getAdjustFactor(factors, tdxdatasource.TimestampToDayDate(result[i].Date))
+		if adjustType == ADJUST_FORWARD {
+			factor /= latest
+		}
+
+		result[i].Open *= factor
+		result[i].Close *= factor
+		result[i].High *= factor
+		result[i].Low *= factor
+	}
+	return nil, result
+}
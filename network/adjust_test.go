@@ -0,0 +1,150 @@
+package network
+
+import (
+	"math"
+	"testing"
+	"github.com/stephenlyu/tds/datasource/tdx"
+	"github.com/stephenlyu/tds/entity"
+)
+
+func buildDays(dates []uint32, closes []float64) []entity.Record {
+	records := []entity.Record{}
+	for i, d := range dates {
+		records = append(records, entity.Record{
+			Date: tdxdatasource.DayDateToTimestamp(d),
+			Open: closes[i], Close: closes[i], High: closes[i] + 1, Low: closes[i] - 1,
+			Volume: 100, Amount: 100 * closes[i],
+		})
+	}
+	return records
+}
+
+func floatsEqual(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i] - b[i]) > 1e-6 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGetExRightPrice(t *testing.T) {
+	cases := []struct {
+		preClose float64
+		item InfoExItem
+		expect float64
+	}{
+		{20, InfoExItem{DeliveredShares: 1}, 10},								// 10送10
+		{10.5, InfoExItem{Bonus: 0.5}, 10},										// 10派5
+		{11.5, InfoExItem{RationedShares: 0.3, RationedSharePrice: 5}, 10},		// 10配3，配股价5元
+		{16, InfoExItem{Bonus: 1, DeliveredShares: 0.5}, 10},					// 10送5派10
+	}
+
+	for i, c := range cases {
+		if price := GetExRightPrice(c.preClose, &c.item); math.Abs(price - c.expect) > 1e-6 {
+			t.Fatalf("case %d: expect %f, got %f", i, c.expect, price)
+		}
+	}
+}
+
+func TestAdjustRecords(t *testing.T) {
+	dates := []uint32{20180102, 20180103, 20180104, 20180105, 20180108}
+	closes := []float64{19, 20, 10, 10.5, 10}
+	records := buildDays(dates, closes)
+	infoEx := []*InfoExItem{
+		{Date: 20180108, Bonus: 0.5},
+		{Date: 20170601, Bonus: 100},				// 上市之前，忽略
+		{Date: 20180104, DeliveredShares: 1},
+	}
+
+	factors := GetAdjustFactors(records, infoEx)
+	if len(factors) != 2 || factors[0].Date != 20180104 || !floatsEqual([]float64{factors[0].Factor, factors[1].Factor}, []float64{2, 2.1}) {
+		t.Fatalf("bad factors: %+v", factors)
+	}
+
+	err, forward := AdjustRecords(records, factors, ADJUST_FORWARD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err, backward := AdjustRecords(records, factors, ADJUST_BACKWARD)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forwardCloses := []float64{}
+	backwardCloses := []float64{}
+	for i := range records {
+		forwardCloses = append(forwardCloses, forward[i].Close)
+		backwardCloses = append(backwardCloses, backward[i].Close)
+		if forward[i].Volume != records[i].Volume || forward[i].Date != records[i].Date {
+			t.Fatalf("volume and date should not change: %+v", forward[i])
+		}
+	}
+	if !floatsEqual(forwardCloses, []float64{19 / 2.1, 20 / 2.1, 20 / 2.1, 21 / 2.1, 10}) {
+		t.Fatalf("bad forward closes: %v", forwardCloses)
+	}
+	if !floatsEqual(backwardCloses, []float64{19, 20, 20, 21, 21}) {
+		t.Fatalf("bad backward closes: %v", backwardCloses)
+	}
+	if math.Abs(backward[3].High - 23) > 1e-6 || records[3].Close != 10.5 {
+		t.Fatalf("bad backward high: %f", backward[3].High)
+	}
+
+	err, raw := AdjustRecords(records, factors, ADJUST_NONE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !floatsEqual([]float64{raw[2].Close}, []float64{10}) {
+		t.Fatalf("bad raw close: %f", raw[2].Close)
+	}
+	if err, _ := AdjustRecords(records, factors, 3); err != ErrBadAdjustType {
+		t.Fatalf("expect ErrBadAdjustType, got %v", err)
+	}
+}
+
+// 复权价格不随请求的记录范围变化，窗口之前的事件也要计入
+func TestAdjustWindow(t *testing.T) {
+	days := buildDays([]uint32{20180102, 20180103, 20180104, 20180105, 20180108}, []float64{19, 20, 10, 10.5, 10})
+	factors := GetAdjustFactors(days, []*InfoExItem{
+		{Date: 20180104, DeliveredShares: 1},
+		{Date: 20180108, Bonus: 0.5},
+	})
+
+	for _, adjustType := range []int{ADJUST_FORWARD, ADJUST_BACKWARD} {
+		_, all := AdjustRecords(days, factors, adjustType)
+		for start := 0; start < len(days); start++ {
+			_, window := AdjustRecords(days[start:], factors, adjustType)
+			for i := range window {
+				if math.Abs(window[i].Close - all[start + i].Close) > 1e-6 {
+					t.Fatalf("adjust type %d, window from %d: expect %f, got %f", adjustType, start, all[start + i].Close, window[i].Close)
+				}
+			}
+		}
+	}
+
+	// 分钟线使用所在日期的因子
+	minutes := []entity.Record{{Date: tdxdatasource.DayDateToTimestamp(20180105) + 600 * 60000, Close: 10.5}}
+	_, adjusted := AdjustRecords(minutes, factors, ADJUST_BACKWARD)
+	if math.Abs(adjusted[0].Close - 21) > 1e-6 {
+		t.Fatalf("bad minute close: %f", adjusted[0].Close)
+	}
+}
+
+// 同一天的多个事件及停牌期间的事件依次作用
+func TestAdjustFactorsMultipleItems(t *testing.T) {
+	records := buildDays([]uint32{20180102, 20180110}, []float64{16.5, 10})
+	infoEx := []*InfoExItem{
+		{Date: 20180105, Bonus: 0.5},
+		{Date: 20180110, DeliveredShares: 0.5},
+	}
+	factors := GetAdjustFactors(records, infoEx)
+	if len(factors) != 1 || factors[0].Date != 20180110 || !floatsEqual([]float64{factors[0].Factor}, []float64{1.546875}) {
+		t.Fatalf("bad factors: %+v", factors)
+	}
+	if len(GetAdjustFactors(nil, infoEx)) != 0 {
+		t.Fatal("expect empty factors")
+	}
+}
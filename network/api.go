@@ -1,8 +1,8 @@
 package network
 
 import (
+	"context"
 	"sync"
-	"gopkg.in/fatih/pool.v2"
 	"net"
 	"bytes"
 	"time"
@@ -10,17 +10,34 @@ import (
 	"github.com/stephenlyu/tds/entity"
 	"os"
 	"fmt"
+	"errors"
+	"sync/atomic"
+	"github.com/z-ray/log"
 )
 
 type API struct {
 	logEnabled 		bool
 	logFile			*os.File
+	recorder		atomic.Value		// *Recorder
 
 	seqId			uint32
 	lock    		sync.Mutex
 
-	timeout 		int					// 毫秒数
-	pool 			pool.Pool
+	options			Options
+	counters		poolCounters
+
+	hostLock		sync.Mutex
+	hosts			[]string			// 按延迟排序
+	hostIndex		int
+	pool 			*connPool
+
+	keepAliveStop	chan struct{}
+
+	muxLock			sync.Mutex
+	muxEnabled		bool
+	muxConns		[]*muxConn
+	muxIndex		int
+	muxGen			int					// closeMuxConns时递增
 }
 
 func CreateAPI(host string) (error, *API) {
@@ -33,6 +50,28 @@ func CreateAPI(host string) (error, *API) {
 	return nil, api
 }
 
+// CreateAPIWithHosts ranks hosts by handshake latency and fails over between them, it does not fail back
+// to a better ranked host.
+func CreateAPIWithHosts(hosts []string) (error, *API) {
+	api := &API {}
+	err := api.InitializeWithHosts(hosts)
+	if err != nil {
+		return err, nil
+	}
+
+	return nil, api
+}
+
+func CreateAPIWithOptions(options *Options) (error, *API) {
+	api := &API {}
+	err := api.InitializeWithOptions(options)
+	if err != nil {
+		return err, nil
+	}
+
+	return nil, api
+}
+
 func (this *API) SetLogEnabled(logEnabled bool) {
 	if this.logEnabled == logEnabled {
 		return
@@ -40,6 +79,8 @@ func (this *API) SetLogEnabled(logEnabled bool) {
 
 	this.logEnabled = logEnabled
 
+	this.lock.Lock()
+	defer this.lock.Unlock()
 	if this.logEnabled {
 		var err error
 		this.logFile, err = os.Create("raw.dat")
@@ -55,65 +96,185 @@ func (this *API) SetLogEnabled(logEnabled bool) {
 }
 
 func (this *API) SetTimeOut(timeout int) {
-	this.timeout = timeout
+	this.options.ReadTimeout = time.Duration(timeout) * time.Millisecond
+	this.options.WriteTimeout = this.options.ReadTimeout
 }
 
 func (this *API) Initialize(host string) error {
-	sendReq := func(conn net.Conn, reqHex string) error {
-		reqData, _ := hex.DecodeString(reqHex)
+	return this.InitializeWithHosts([]string{host})
+}
 
-		_, err := conn.Write(reqData)
-		if err != nil {
-			return err
-		}
+func (this *API) InitializeWithHosts(hosts []string) error {
+	options := DefaultOptions()
+	options.Hosts = hosts
+	return this.InitializeWithOptions(options)
+}
 
-		err, _ = ReadResp(conn)
-		return err
+func (this *API) InitializeWithOptions(options *Options) error {
+	hosts := options.Hosts
+	if len(hosts) == 0 {
+		return errors.New("no host")
+	}
+	if options.MaxCap <= 0 || options.InitialCap < 0 || options.InitialCap > options.MaxCap {
+		return errors.New("bad pool capacity")
+	}
+	if options.MaxConns < 0 || (options.MaxConns > 0 && options.MaxConns < options.MaxCap) {
+		return errors.New("bad max conns")
 	}
 
-	factory := func() (net.Conn, error) {
-		conn, err := net.Dial("tcp", host)
-		if err != nil {
-			return conn, err
+	this.options = *options
+	if this.options.MuxConns <= 0 {
+		this.options.MuxConns = MUX_CONN_COUNT
+	}
+
+	if len(hosts) > 1 {
+		stats := RankHostsWithDialer(this.dialer(), hosts, PROBE_TIMEOUT)
+		hosts = make([]string, len(stats))
+		for i, stat := range stats {
+			hosts[i] = stat.Host
 		}
+	}
 
-		// Connection Prolog
-		err = sendReq(conn, "0c0218940001030003000d0001")
-		if err != nil {
-			conn.Close()
-			return nil, err
+	var err error
+	for i, host := range hosts {
+		var p *connPool
+		p, err = this.createPool(host)
+		if err == nil {
+			this.hostLock.Lock()
+			this.hosts = hosts
+			this.hostIndex = i
+			this.pool = p
+			this.hostLock.Unlock()
+			return nil
 		}
+	}
 
-		err = sendReq(conn, "0c031899000120002000db0fb3a4bdadd6a4c8af0000009a993141090000000000000000000000000003")
+	return err
+}
+
+func (this *API) dialer() Dialer {
+	if this.options.Dialer != nil {
+		return this.options.Dialer
+	}
+	return &net.Dialer{}
+}
+
+// dialHost connects to host and does the handshake, DialTimeout limits both even with Options.Dialer.
+func (this *API) dialHost(ctx context.Context, host string) (net.Conn, error) {
+	if this.options.DialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, this.options.DialTimeout)
+		defer cancel()
+	}
+
+	conn, err := this.dialer().DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+
+	unbind := bindDeadline(ctx, conn)
+	err = handshake(conn)
+	unbind()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (this *API) createPool(host string) (*connPool, error) {
+	factory := func(ctx context.Context) (net.Conn, error) {
+		conn, err := this.dialHost(ctx, host)
 		if err != nil {
-			conn.Close()
 			return nil, err
 		}
-		return conn, nil
+		atomic.AddInt64(&this.counters.created, 1)
+		return newTrackedConn(conn), nil
 	}
 
-	p, err := pool.NewChannelPool(5, 5, factory)
-	if err != nil {
-		return err
+	return newConnPool(this.options.InitialCap, this.options.MaxCap, this.options.MaxConns, factory)
+}
+
+func (this *API) getPool() *connPool {
+	this.hostLock.Lock()
+	defer this.hostLock.Unlock()
+	return this.pool
+}
+
+// CurrentHost returns the host currently serving requests.
+func (this *API) CurrentHost() string {
+	this.hostLock.Lock()
+	defer this.hostLock.Unlock()
+	if len(this.hosts) == 0 {
+		return ""
 	}
+	return this.hosts[this.hostIndex]
+}
 
-	this.pool = p
+func (this *API) hostCount() int {
+	this.hostLock.Lock()
+	defer this.hostLock.Unlock()
+	return len(this.hosts)
+}
 
-	this.timeout = 10 * 1000
+// failover switches to the next reachable host if p is still the active pool. The new pool is created
+// without hostLock, so requests are not blocked by dialing. There is no fail back, the API stays on the new
+// host until it fails too, create a new API to rank the hosts again.
+func (this *API) failover(p *connPool) error {
+	this.hostLock.Lock()
+	if this.pool != p {
+		// Already switched by another request
+		this.hostLock.Unlock()
+		return nil
+	}
+	hosts := this.hosts
+	hostIndex := this.hostIndex
+	this.hostLock.Unlock()
+
+	var err error = errors.New("no more host")
+	for i := 1; i < len(hosts); i++ {
+		index := (hostIndex + i) % len(hosts)
+		var newPool *connPool
+		newPool, err = this.createPool(hosts[index])
+		if err != nil {
+			continue
+		}
 
-	return nil
+		this.hostLock.Lock()
+		if this.pool != p {
+			// Switched by another request or closed while creating
+			this.hostLock.Unlock()
+			newPool.Close()
+			return nil
+		}
+		this.hostIndex = index
+		this.pool = newPool
+		this.hostLock.Unlock()
+
+		p.Close()
+		go this.resetMuxConns()
+		return nil
+	}
+	return err
 }
 
 func (this *API) Cleanup() error {
+	this.SetKeepAlive(0)
+	this.SetMultiplexEnabled(false)
+
+	this.hostLock.Lock()
 	if this.pool != nil {
 		this.pool.Close()
 		this.pool = nil
 	}
+	this.hostLock.Unlock()
 
+	this.lock.Lock()
 	if this.logFile != nil {
 		this.logFile.Close()
 		this.logFile = nil
 	}
+	this.lock.Unlock()
 	return nil
 }
 
@@ -125,212 +286,531 @@ func (this *API) nextSeqId() uint32 {
 }
 
 func (this *API) markConnUnusable(conn interface{}) {
-	if poolConn, ok := conn.(*pool.PoolConn); ok {
+	if poolConn, ok := conn.(*pooledConn); ok {
 		poolConn.MarkUnusable()
+		atomic.AddInt64(&this.counters.discarded, 1)
 	}
 }
 
-func (this *API) sendReq(data []byte) (error, []byte) {
-	conn, err := this.pool.Get()
+func (this *API) readDeadline(ctx context.Context) time.Time {
+	return this.deadline(ctx, this.options.ReadTimeout)
+}
+
+func (this *API) writeDeadline(ctx context.Context) time.Time {
+	return this.deadline(ctx, this.options.WriteTimeout)
+}
+
+func (this *API) deadline(ctx context.Context, timeout time.Duration) time.Time {
+	var d time.Time
+	if timeout > 0 {
+		d = time.Now().Add(timeout)
+	}
+	if ctxDeadline, ok := ctx.Deadline(); ok && (d.IsZero() || ctxDeadline.Before(d)) {
+		d = ctxDeadline
+	}
+	return d
+}
+
+// dropExpiredConn closes conn and returns true if it exceeds IdleTimeout or MaxLifetime.
+func (this *API) dropExpiredConn(conn net.Conn) bool {
+	if !this.isConnExpired(conn) {
+		return false
+	}
+
+	if poolConn, ok := conn.(*pooledConn); ok {
+		poolConn.MarkUnusable()
+	}
+	conn.Close()
+	atomic.AddInt64(&this.counters.expired, 1)
+	return true
+}
+
+// getPooledConn drops connections which exceed IdleTimeout or MaxLifetime.
+func (this *API) getPooledConn(ctx context.Context, p *connPool) (net.Conn, error) {
+	for {
+		conn, err := p.Get(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if !this.dropExpiredConn(conn) {
+			return conn, nil
+		}
+	}
+}
+
+func (this *API) getConn(ctx context.Context, p *connPool) (error, net.Conn) {
+	conn, err := this.getPooledConn(ctx, p)
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err(), nil
+	}
+	return err, conn
+}
+
+// SetRecorder records every request and its response, nil stops recording.
+func (this *API) SetRecorder(recorder *Recorder) {
+	this.recorder.Store(recorder)
+}
+
+func (this *API) getRecorder() *Recorder {
+	recorder, _ := this.recorder.Load().(*Recorder)
+	return recorder
+}
+
+func (this *API) sendReqContext(ctx context.Context, data []byte) (error, []byte) {
+	err, respData := this.sendReqWithFailover(ctx, data)
+
+	if recorder := this.getRecorder(); recorder != nil && ctx.Err() == nil {
+		if err1 := recorder.Record(data, respData, err); err1 != nil {
+			log.Errorf("API.sendReqContext - record fail, error: %v", err1)
+		}
+	}
+	return err, respData
+}
+
+func (this *API) sendReqWithFailover(ctx context.Context, data []byte) (error, []byte) {
+	for retryTimes := 0; ; retryTimes++ {
+		if err := ctx.Err(); err != nil {
+			return err, nil
+		}
+
+		p := this.getPool()
+		if p == nil {
+			return ErrAPIClosed, nil
+		}
+
+		var err error
+		var respData []byte
+		if this.isMuxEnabled() {
+			err, respData = this.sendReqMux(ctx, data)
+		} else {
+			err, respData = this.sendReqWithPool(ctx, p, data)
+		}
+		if err == nil || ctx.Err() != nil || !IsNetworkError(err) || retryTimes + 1 >= this.hostCount() {
+			return err, respData
+		}
+
+		if this.failover(p) != nil {
+			return err, nil
+		}
+	}
+}
+
+func (this *API) sendReqWithPool(ctx context.Context, p *connPool, data []byte) (error, []byte) {
+	err, conn := this.getConn(ctx, p)
 	if err != nil {
 		return err, nil
 	}
-	defer conn.Close()
+	atomic.AddInt64(&this.counters.inUse, 1)
+	defer func() {
+		conn.Close()
+		atomic.AddInt64(&this.counters.inUse, -1)
+	}()
 
-	if this.timeout > 0 {
-		conn.SetDeadline(time.Now().Add(time.Duration(this.timeout) * time.Millisecond))
-	}
-	_, err = conn.Write(data)
+	err, respData := this.roundTrip(ctx, conn, data)
 	if err != nil {
 		this.markConnUnusable(conn)
 		return err, nil
 	}
 
-	if this.timeout > 0 {
-		conn.SetDeadline(time.Now().Add(time.Duration(this.timeout) * time.Millisecond))
+	this.touchConn(conn)
+	return nil, respData
+}
+
+func (this *API) roundTrip(ctx context.Context, conn net.Conn, data []byte) (error, []byte) {
+	// Unblock pending read/write once ctx is cancelled
+	done := make(chan struct{})
+	defer close(done)
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				conn.SetDeadline(time.Now())
+			case <-done:
+			}
+		}()
 	}
+
+	conn.SetDeadline(this.writeDeadline(ctx))
+	_, err := conn.Write(data)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err(), nil
+		}
+		return err, nil
+	}
+
+	conn.SetDeadline(this.readDeadline(ctx))
 	err, respData := ReadResp(conn)
 	if err != nil {
-		this.markConnUnusable(conn)
+		if ctx.Err() != nil {
+			return ctx.Err(), nil
+		}
 		return err, nil
 	}
 
-	return err, respData
+	return nil, respData
+}
+
+// Do sends req and decodes the response with the registered command, the seq id of req is assigned here.
+func (this *API) Do(ctx context.Context, req WritableRequest) (error, interface{}) {
+	command := GetCommand(req.GetCmd())
+	if command == nil || command.DecodeResp == nil {
+		return &ProtocolError{Cmd: req.GetCmd(), Err: ErrUnknownCmd}, nil
+	}
+
+	err, respData := this.send(ctx, req)
+	if err != nil {
+		return err, nil
+	}
+
+	if command.LogResp {
+		this.logResp(respData)
+	}
+	return command.DecodeResp(req, respData)
+}
+
+// send assigns the seq id of req and returns the raw response
+func (this *API) send(ctx context.Context, req WritableRequest) (error, []byte) {
+	req.SetSeqId(this.nextSeqId())
+	buf := new(bytes.Buffer)
+	req.Write(buf)
+
+	return this.sendReqContext(ctx, buf.Bytes())
+}
+
+func (this *API) logResp(respData []byte) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if this.logFile != nil {
+		this.logFile.Write([]byte(hex.EncodeToString(respData) + "\n\n"))
+	}
 }
 
 func (this *API) GetInfoEx(securities []*entity.Security) (error, map[string][]*InfoExItem) {
-	req := NewInfoExReq(this.nextSeqId())
+	return this.GetInfoExContext(context.Background(), securities)
+}
+
+func (this *API) GetInfoExContext(ctx context.Context, securities []*entity.Security) (error, map[string][]*InfoExItem) {
+	req := NewInfoExReq(0)
 	for _, security := range securities {
 		req.AddCode(security)
 	}
-	buf := new(bytes.Buffer)
-	req.Write(buf)
 
-	err, respData := this.sendReq(buf.Bytes())
+	err, result := this.Do(ctx, req)
 	if err != nil {
 		return err, nil
 	}
+	return nil, DividendItems(result.(map[string][]*InfoExEvent))
+}
 
-	parser := NewInfoExParser(req, respData)
-	return parser.Parse()
+func (this *API) GetInfoExEvents(securities []*entity.Security) (error, map[string][]*InfoExEvent) {
+	return this.GetInfoExEventsContext(context.Background(), securities)
 }
 
-func (this *API) GetFinance(securities []*entity.Security) (error, map[string]*Finance) {
-	req := NewFinanceReq(this.nextSeqId())
+// GetInfoExEventsContext returns XDXR events of all categories, GetInfoEx returns XDXR_DIVIDEND only.
+func (this *API) GetInfoExEventsContext(ctx context.Context, securities []*entity.Security) (error, map[string][]*InfoExEvent) {
+	req := NewInfoExReq(0)
 	for _, security := range securities {
 		req.AddCode(security)
 	}
-	buf := new(bytes.Buffer)
-	req.Write(buf)
 
-	err, respData := this.sendReq(buf.Bytes())
+	err, result := this.Do(ctx, req)
 	if err != nil {
 		return err, nil
 	}
+	return nil, result.(map[string][]*InfoExEvent)
+}
 
-	parser := NewFinanceParser(req, respData)
-	return parser.Parse()
+func (this *API) GetFinance(securities []*entity.Security) (error, map[string]*Finance) {
+	return this.GetFinanceContext(context.Background(), securities)
 }
 
-func (this *API) GetBid(securities []*entity.Security) (error, map[string]*Bid) {
-	req := NewBidReq(this.nextSeqId())
+func (this *API) GetFinanceContext(ctx context.Context, securities []*entity.Security) (error, map[string]*Finance) {
+	req := NewFinanceReq(0)
 	for _, security := range securities {
 		req.AddCode(security)
 	}
-	buf := new(bytes.Buffer)
-	req.Write(buf)
 
-	err, respData := this.sendReq(buf.Bytes())
+	err, result := this.Do(ctx, req)
 	if err != nil {
 		return err, nil
 	}
+	return nil, result.(map[string]*Finance)
+}
 
-	if this.logFile != nil {
-		this.lock.Lock()
-		this.logFile.Write([]byte(hex.EncodeToString(respData) + "\n\n"))
-		this.lock.Unlock()
+func (this *API) GetBid(securities []*entity.Security) (error, map[string]*Bid) {
+	return this.GetBidContext(context.Background(), securities)
+}
+
+func (this *API) GetBidContext(ctx context.Context, securities []*entity.Security) (error, map[string]*Bid) {
+	req := NewBidReq(0)
+	for _, security := range securities {
+		req.AddCode(security)
 	}
 
-	parser := NewBidParser(req, respData)
-	return parser.Parse()
+	err, result := this.Do(ctx, req)
+	if err != nil {
+		return err, nil
+	}
+	return nil, result.(map[string]*Bid)
 }
 
 func (this *API) GetInstantTransaction(security *entity.Security, offset, count uint16) (error, []Transaction) {
-	req := NewInstantTransReq(this.nextSeqId(), security, offset, count)
-	buf := new(bytes.Buffer)
-	req.Write(buf)
+	return this.GetInstantTransactionContext(context.Background(), security, offset, count)
+}
 
-	err, respData := this.sendReq(buf.Bytes())
+func (this *API) GetInstantTransactionContext(ctx context.Context, security *entity.Security, offset, count uint16) (error, []Transaction) {
+	err, result := this.Do(ctx, NewInstantTransReq(0, security, offset, count))
 	if err != nil {
 		return err, nil
 	}
-
-	parser := NewInstantTransParser(req, respData)
-	return parser.Parse()
+	return nil, result.([]Transaction)
 }
 
 func (this *API) GetHistoryTransaction(security *entity.Security, date uint32, offset, count uint16) (error, []Transaction) {
-	req := NewHisTransReq(this.nextSeqId(), date, security, offset, count)
-	buf := new(bytes.Buffer)
-	req.Write(buf)
+	return this.GetHistoryTransactionContext(context.Background(), security, date, offset, count)
+}
 
-	err, respData := this.sendReq(buf.Bytes())
+func (this *API) GetHistoryTransactionContext(ctx context.Context, security *entity.Security, date uint32, offset, count uint16) (error, []Transaction) {
+	err, result := this.Do(ctx, NewHisTransReq(0, date, security, offset, count))
 	if err != nil {
 		return err, nil
 	}
-
-	parser := NewHisTransParser(req, respData)
-	return parser.Parse()
+	return nil, result.([]Transaction)
 }
 
 func (this *API) GetPeriodData(security *entity.Security, period, offset, count uint16) (error, []entity.Record) {
-	req := NewPeriodDataReq(this.nextSeqId(), security, period, offset, count)
-	buf := new(bytes.Buffer)
-	req.Write(buf)
+	return this.GetPeriodDataContext(context.Background(), security, period, offset, count)
+}
 
-	err, respData := this.sendReq(buf.Bytes())
+func (this *API) GetPeriodDataContext(ctx context.Context, security *entity.Security, period, offset, count uint16) (error, []entity.Record) {
+	err, result := this.Do(ctx, NewPeriodDataReq(0, security, period, offset, count))
 	if err != nil {
 		return err, nil
 	}
+	return nil, PlainRecords(result.([]IndexRecord))
+}
 
-	parser := NewPeriodDataParser(req, respData)
-	return parser.Parse()
+func (this *API) GetIndexPeriodData(security *entity.Security, period, offset, count uint16) (error, []IndexRecord) {
+	return this.GetIndexPeriodDataContext(context.Background(), security, period, offset, count)
 }
 
-func (this *API) GetPeriodHisData(security *entity.Security, period uint16, startDate, EndDate uint32) (error, []byte) {
-	req := NewPeriodHisDataReq(this.nextSeqId(), security, period, startDate, EndDate)
-	buf := new(bytes.Buffer)
-	req.Write(buf)
+// GetIndexPeriodDataContext decodes the response as index records whatever the code is,
+// use it for indices IsIndexCode can not tell.
+func (this *API) GetIndexPeriodDataContext(ctx context.Context, security *entity.Security, period, offset, count uint16) (error, []IndexRecord) {
+	req := NewPeriodDataReq(0, security, period, offset, count)
+	req.Index = true
+	err, result := this.Do(ctx, req)
+	if err != nil {
+		return err, nil
+	}
+	return nil, result.([]IndexRecord)
+}
+
+func (this *API) GetMinuteTimeData(security *entity.Security) (error, []MinuteTimePoint) {
+	return this.GetMinuteTimeDataContext(context.Background(), security)
+}
+
+// GetMinuteTimeDataContext returns the time-share chart of today.
+func (this *API) GetMinuteTimeDataContext(ctx context.Context, security *entity.Security) (error, []MinuteTimePoint) {
+	err, result := this.Do(ctx, NewMinuteTimeReq(0, security))
+	if err != nil {
+		return err, nil
+	}
+	return nil, result.([]MinuteTimePoint)
+}
+
+func (this *API) GetHistoryMinuteTimeData(security *entity.Security, date uint32) (error, []MinuteTimePoint) {
+	return this.GetHistoryMinuteTimeDataContext(context.Background(), security, date)
+}
 
-	err, respData := this.sendReq(buf.Bytes())
+// GetHistoryMinuteTimeDataContext returns the time-share chart of date, e.g. 20181102.
+func (this *API) GetHistoryMinuteTimeDataContext(ctx context.Context, security *entity.Security, date uint32) (error, []MinuteTimePoint) {
+	err, result := this.Do(ctx, NewHisMinuteTimeReq(0, date, security))
 	if err != nil {
 		return err, nil
 	}
+	return nil, result.([]MinuteTimePoint)
+}
+
+func (this *API) GetPeriodHisData(security *entity.Security, period uint16, startDate, EndDate uint32) (error, []byte) {
+	return this.GetPeriodHisDataContext(context.Background(), security, period, startDate, EndDate)
+}
 
-	parser := NewPeriodHisDataParser(req, respData)
-	return parser.Parse()
+func (this *API) GetPeriodHisDataContext(ctx context.Context, security *entity.Security, period uint16, startDate, EndDate uint32) (error, []byte) {
+	err, result := this.Do(ctx, NewPeriodHisDataReq(0, security, period, startDate, EndDate))
+	if err != nil {
+		return err, nil
+	}
+	return nil, result.([]byte)
 }
 
 func (this *API) GetFileLength(fileName string) (error, uint32) {
-	req := NewGetFileLenReq(this.nextSeqId(), fileName)
-	buf := new(bytes.Buffer)
-	req.Write(buf)
+	return this.GetFileLengthContext(context.Background(), fileName)
+}
 
-	err, respData := this.sendReq(buf.Bytes())
+func (this *API) GetFileLengthContext(ctx context.Context, fileName string) (error, uint32) {
+	err, result := this.Do(ctx, NewGetFileLenReq(0, fileName))
 	if err != nil {
 		return err, 0
 	}
-
-	parser := NewGetFileLenParser(req, respData)
-	return parser.Parse()
+	return nil, result.(uint32)
 }
 
 func (this *API) GetFileData(fileName string, offset uint32, length uint32) (error, uint32, []byte) {
-	req := NewGetFileDataReq(this.nextSeqId(), fileName, offset, length)
-	buf := new(bytes.Buffer)
-	req.Write(buf)
+	return this.GetFileDataContext(context.Background(), fileName, offset, length)
+}
 
-	err, respData := this.sendReq(buf.Bytes())
+func (this *API) GetFileDataContext(ctx context.Context, fileName string, offset uint32, length uint32) (error, uint32, []byte) {
+	err, result := this.Do(ctx, NewGetFileDataReq(0, fileName, offset, length))
 	if err != nil {
 		return err, 0, nil
 	}
-
-	parser := NewGetFileDataParser(req, respData)
-	return parser.Parse()
+	fileData := result.(*FileData)
+	return nil, fileData.Length, fileData.Data
 }
 
 func (this *API) GetNamesLength(block uint16) (error, uint32) {
-	req := NewNamesLenReq(this.nextSeqId(), block)
-	buf := new(bytes.Buffer)
-	req.Write(buf)
+	return this.GetNamesLengthContext(context.Background(), block)
+}
 
-	err, respData := this.sendReq(buf.Bytes())
+func (this *API) GetNamesLengthContext(ctx context.Context, block uint16) (error, uint32) {
+	err, result := this.Do(ctx, NewNamesLenReq(0, block))
 	if err != nil {
 		return err, 0
 	}
-
-	parser := NewNamesLenParser(req, respData)
-	return parser.Parse()
+	return nil, result.(uint32)
 }
 
 func (this *API) GetNamesData(block uint16, offset uint16) (error, uint16, []byte) {
-	req := NewNamesReq(this.nextSeqId(), block, offset)
-	buf := new(bytes.Buffer)
-	req.Write(buf)
+	return this.GetNamesDataContext(context.Background(), block, offset)
+}
 
-	err, respData := this.sendReq(buf.Bytes())
+func (this *API) GetNamesDataContext(ctx context.Context, block uint16, offset uint16) (error, uint16, []byte) {
+	err, result := this.Do(ctx, NewNamesReq(0, block, offset))
 	if err != nil {
 		return err, 0, nil
 	}
+	names := result.(*NamesData)
+	return nil, names.Count, names.Data
+}
+
+func (this *API) GetSecurityCount(market uint16) (error, uint32) {
+	return this.GetSecurityCountContext(context.Background(), market)
+}
+
+// GetSecurityCountContext returns the count of securities in market, one of MARKET_SZ, MARKET_SH and MARKET_BJ.
+func (this *API) GetSecurityCountContext(ctx context.Context, market uint16) (error, uint32) {
+	return this.GetNamesLengthContext(ctx, market)
+}
+
+func (this *API) GetSecurityList(market uint16, offset uint16) (error, []*SecurityInfo) {
+	return this.GetSecurityListContext(context.Background(), market, offset)
+}
+
+// GetSecurityListContext returns at most 1000 securities of market starting from offset.
+func (this *API) GetSecurityListContext(ctx context.Context, market uint16, offset uint16) (error, []*SecurityInfo) {
+	err, _, data := this.GetNamesDataContext(ctx, market, offset)
+	if err != nil {
+		return err, nil
+	}
 
-	parser := NewNamesParser(req, respData)
-	return parser.Parse()
+	err, result := ParseSecurityList(market, data)
+	if err != nil {
+		return &ProtocolError{Cmd: CMD_NAMES, Data: data, Err: err}, nil
+	}
+	return nil, result
 }
 
 func (this *API) GetMinuteData(security *entity.Security, offset, count uint16) (error, []entity.Record) {
-	return this.GetPeriodData(security, PERIOD_MINUTE, offset, count)
+	return this.GetMinuteDataContext(context.Background(), security, offset, count)
+}
+
+func (this *API) GetMinuteDataContext(ctx context.Context, security *entity.Security, offset, count uint16) (error, []entity.Record) {
+	return this.GetPeriodDataContext(ctx, security, PERIOD_MINUTE, offset, count)
 }
 
 func (this *API) GetDayData(security *entity.Security, offset, count uint16) (error, []entity.Record) {
-	return this.GetPeriodData(security, PERIOD_DAY, offset, count)
+	return this.GetDayDataContext(context.Background(), security, offset, count)
+}
+
+func (this *API) GetDayDataContext(ctx context.Context, security *entity.Security, offset, count uint16) (error, []entity.Record) {
+	return this.GetPeriodDataContext(ctx, security, PERIOD_DAY, offset, count)
+}
+
+func (this *API) HeartBeat() error {
+	return this.HeartBeatContext(context.Background())
+}
+
+func (this *API) HeartBeatContext(ctx context.Context) error {
+	err, _ := this.Do(ctx, NewHeartBeatReq(0))
+	return err
+}
+
+// SetKeepAlive sends heartbeat on idle pooled connections every interval and evicts dead ones.
+// interval <= 0 stops keep alive.
+func (this *API) SetKeepAlive(interval time.Duration) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	if this.keepAliveStop != nil {
+		close(this.keepAliveStop)
+		this.keepAliveStop = nil
+	}
+
+	if interval <= 0 {
+		return
+	}
+
+	stopCh := make(chan struct{})
+	this.keepAliveStop = stopCh
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				this.checkIdleConns()
+			}
+		}
+	}()
+}
+
+func (this *API) checkIdleConns() {
+	p := this.getPool()
+	if p == nil {
+		return
+	}
+
+	// Idle connections are handed out in FIFO order, so every idle one is checked once.
+	// Stop once the idle ones are taken by requests, never dial here.
+	n := p.Len()
+	for i := 0; i < n; i++ {
+		conn, err := p.TryGet()
+		if conn == nil || err != nil {
+			return
+		}
+		if this.dropExpiredConn(conn) {
+			continue
+		}
+
+		req := NewHeartBeatReq(this.nextSeqId())
+		buf := new(bytes.Buffer)
+		req.Write(buf)
+
+		err, respData := this.roundTrip(context.Background(), conn, buf.Bytes())
+		if err == nil {
+			err = NewHeartBeatParser(req, respData).Parse()
+		}
+		if err != nil {
+			log.Errorf("API.checkIdleConns - heart beat fail, evict connection, error: %v", err)
+			this.markConnUnusable(conn)
+		}
+		conn.Close()
+	}
 }
@@ -0,0 +1,149 @@
+package network
+
+import (
+	"time"
+	"github.com/stephenlyu/tds/datasource/tdx"
+	"github.com/stephenlyu/tds/entity"
+)
+
+// K线类型
+const (
+	BAR_TIME = 0
+	BAR_VOLUME = 1				// 成交量达到Threshold股
+	BAR_AMOUNT = 2				// 成交额达到Threshold元
+)
+
+// A股交易时段，以分钟计
+const (
+	SESSION_AM_START = 9 * 60 + 30
+	SESSION_AM_END = 11 * 60 + 30
+	SESSION_PM_START = 13 * 60
+	SESSION_PM_END = 15 * 60
+)
+
+// BarBuilder aggregates transactions of one day into bars. Time bars are labeled by their end time like
+// the M1 bars of the server: the 09:25 auction goes to the first bar, the bars do not cross the lunch
+// break and transactions after 15:00 are dropped. Only the bars with transactions are built.
+type BarBuilder struct {
+	Kind int
+	Interval int				// BAR_TIME的秒数
+	Threshold float64			// BAR_VOLUME的股数或BAR_AMOUNT的金额
+	PriceScale float64			// 成交价格除以PriceScale为元
+	VolumeScale float64			// 每手股数
+}
+
+func NewTimeBarBuilder(interval time.Duration) *BarBuilder {
+	return &BarBuilder{Kind: BAR_TIME, Interval: int(interval / time.Second), PriceScale: 100, VolumeScale: 100}
+}
+
+func NewVolumeBarBuilder(volume float64) *BarBuilder {
+	return &BarBuilder{Kind: BAR_VOLUME, Threshold: volume, PriceScale: 100, VolumeScale: 100}
+}
+
+func NewAmountBarBuilder(amount float64) *BarBuilder {
+	return &BarBuilder{Kind: BAR_AMOUNT, Threshold: amount, PriceScale: 100, VolumeScale: 100}
+}
+
+// tradingSeconds returns the session of the transaction and the seconds from the session start,
+// ok is false for the transactions out of the sessions.
+func tradingSeconds(minute uint16, second int) (sessionStart int, sessionEnd int, offset int, ok bool) {
+	m := int(minute)
+	switch {
+	case m < SESSION_AM_START:				// 集合竞价
+		return SESSION_AM_START, SESSION_AM_END, 0, true
+	case m < SESSION_AM_END:
+		return SESSION_AM_START, SESSION_AM_END, (m - SESSION_AM_START) * 60 + second, true
+	case m == SESSION_AM_END:
+		return SESSION_AM_START, SESSION_AM_END, (SESSION_AM_END - SESSION_AM_START) * 60, true
+	case m >= SESSION_PM_START && m < SESSION_PM_END:
+		return SESSION_PM_START, SESSION_PM_END, (m - SESSION_PM_START) * 60 + second, true
+	case m == SESSION_PM_END:				// 收盘集合竞价
+		return SESSION_PM_START, SESSION_PM_END, (SESSION_PM_END - SESSION_PM_START) * 60, true
+	}
+	return 0, 0, 0, false
+}
+
+// Build returns the bars in time order. Transaction has no seconds, the transactions of one minute
+// are spread evenly over the minute for the bars shorter than one minute.
+func (this *BarBuilder) Build(day uint32, transactions []Transaction) (error, []entity.Record) {
+	switch this.Kind {
+	case BAR_TIME:
+		if this.Interval <= 0 {
+			return ErrBadBarSize, nil
+		}
+	case BAR_VOLUME, BAR_AMOUNT:
+		if this.Threshold <= 0 {
+			return ErrBadBarSize, nil
+		}
+	default:
+		return ErrBadBarSize, nil
+	}
+
+	// 每分钟的成交数
+	minuteCounts := map[uint16]int{}
+	for _, t := range transactions {
+		minuteCounts[t.Minute]++
+	}
+
+	dayTs := tdxdatasource.DayDateToTimestamp(day)
+	result := []entity.Record{}
+	var bar *entity.Record
+
+	index := 0
+	for i, t := range transactions {
+		if i > 0 && t.Minute == transactions[i - 1].Minute {
+			index++
+		} else {
+			index = 0
+		}
+
+		second := index * 60 / minuteCounts[t.Minute]
+		sessionStart, sessionEnd, offset, ok := tradingSeconds(t.Minute, second)
+		if !ok {
+			continue
+		}
+
+		var ts uint64
+		if this.Kind == BAR_TIME {
+			sessionLen := (sessionEnd - sessionStart) * 60
+			end := (offset / this.Interval + 1) * this.Interval
+			if end > sessionLen {
+				end = sessionLen
+			}
+			ts = dayTs + uint64(sessionStart * 60 + end) * 1000
+		} else {
+			ts = dayTs + uint64(int(t.Minute) * 60 + second) * 1000
+		}
+
+		price := float64(t.Price) / this.PriceScale
+		volume := float64(t.Volume) * this.VolumeScale
+
+		if bar != nil && this.Kind == BAR_TIME && bar.Date != ts {
+			result = append(result, *bar)
+			bar = nil
+		}
+		if bar == nil {
+			bar = &entity.Record{Date: ts, Open: price, High: price, Low: price}
+		}
+
+		bar.Date = ts
+		bar.Close = price
+		if price > bar.High {
+			bar.High = price
+		}
+		if price < bar.Low {
+			bar.Low = price
+		}
+		bar.Volume += volume
+		bar.Amount += price * volume
+
+		if (this.Kind == BAR_VOLUME && bar.Volume >= this.Threshold) || (this.Kind == BAR_AMOUNT && bar.Amount >= this.Threshold) {
+			result = append(result, *bar)
+			bar = nil
+		}
+	}
+	if bar != nil {
+		result = append(result, *bar)
+	}
+	return nil, result
+}
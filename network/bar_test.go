@@ -0,0 +1,179 @@
+package network
+
+import (
+	"math"
+	"testing"
+	"time"
+	"github.com/stephenlyu/tds/datasource/tdx"
+	"github.com/stephenlyu/tds/entity"
+)
+
+func barTime(day uint32, hour, minute, second int) uint64 {
+	return tdxdatasource.DayDateToTimestamp(day) + uint64(hour * 3600 + minute * 60 + second) * 1000
+}
+
+func TestTimeBars(t *testing.T) {
+	transactions := []Transaction{
+		{Minute: 9 * 60 + 25, Price: 1000, Volume: 10},			// 集合竞价
+		{Minute: 9 * 60 + 30, Price: 1010, Volume: 1},
+		{Minute: 9 * 60 + 30, Price: 990, Volume: 2},
+		{Minute: 9 * 60 + 30, Price: 1005, Volume: 3},
+		{Minute: 11 * 60 + 29, Price: 1020, Volume: 1},
+		{Minute: 11 * 60 + 30, Price: 1021, Volume: 1},
+		{Minute: 13 * 60, Price: 1022, Volume: 1},
+		{Minute: 15 * 60, Price: 1030, Volume: 5},				// 收盘集合竞价
+		{Minute: 15 * 60 + 5, Price: 1030, Volume: 5},			// 盘后交易
+	}
+
+	err, bars := NewTimeBarBuilder(time.Minute).Build(20181102, transactions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []struct {
+		ts uint64
+		open, high, low, close, volume float64
+	}{
+		{barTime(20181102, 9, 31, 0), 10, 10.1, 9.9, 10.05, 1600},
+		{barTime(20181102, 11, 30, 0), 10.2, 10.21, 10.2, 10.21, 200},
+		{barTime(20181102, 13, 1, 0), 10.22, 10.22, 10.22, 10.22, 100},
+		{barTime(20181102, 15, 0, 0), 10.3, 10.3, 10.3, 10.3, 500},
+	}
+	if len(bars) != len(expect) {
+		t.Fatalf("bad bars: %+v", bars)
+	}
+	for i, e := range expect {
+		bar := bars[i]
+		if bar.Date != e.ts || bar.Open != e.open || bar.High != e.high || bar.Low != e.low || bar.Close != e.close || bar.Volume != e.volume {
+			t.Fatalf("bar %d: expect %+v, got %+v", i, e, bar)
+		}
+	}
+	if math.Abs(bars[0].Amount - 16005) > 1e-6 {
+		t.Fatalf("bad amount: %f", bars[0].Amount)
+	}
+
+	// 20秒K线，同一分钟的3笔成交分布在0、20、40秒
+	err, bars = NewTimeBarBuilder(20 * time.Second).Build(20181102, transactions[:4])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bars) != 3 || bars[0].Date != barTime(20181102, 9, 30, 20) || bars[0].Volume != 1100 || bars[2].Date != barTime(20181102, 9, 31, 0) {
+		t.Fatalf("bad 20s bars: %+v", bars)
+	}
+
+	// 10秒和1秒K线，11:30和15:00的成交归入收盘的K线
+	for _, c := range []struct {
+		interval time.Duration
+		expect []uint64
+	}{
+		{10 * time.Second, []uint64{
+			barTime(20181102, 9, 30, 10), barTime(20181102, 9, 30, 30), barTime(20181102, 9, 30, 50),
+			barTime(20181102, 11, 29, 10), barTime(20181102, 11, 30, 0), barTime(20181102, 13, 0, 10), barTime(20181102, 15, 0, 0),
+		}},
+		{time.Second, []uint64{
+			barTime(20181102, 9, 30, 1), barTime(20181102, 9, 30, 21), barTime(20181102, 9, 30, 41),
+			barTime(20181102, 11, 29, 1), barTime(20181102, 11, 30, 0), barTime(20181102, 13, 0, 1), barTime(20181102, 15, 0, 0),
+		}},
+	} {
+		err, bars = NewTimeBarBuilder(c.interval).Build(20181102, transactions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bars) != len(c.expect) || bars[0].Volume != 1100 {
+			t.Fatalf("bad %v bars: %+v", c.interval, bars)
+		}
+		for i, ts := range c.expect {
+			if bars[i].Date != ts {
+				t.Fatalf("bad %v bar %d: %+v", c.interval, i, bars[i])
+			}
+		}
+	}
+
+	// 7分钟K线不跨越午休，上午最后一根到11:30
+	err, bars = NewTimeBarBuilder(7 * time.Minute).Build(20181102, transactions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bars) != 4 || bars[0].Date != barTime(20181102, 9, 37, 0) || bars[1].Date != barTime(20181102, 11, 30, 0) || bars[1].Volume != 200 || bars[2].Date != barTime(20181102, 13, 7, 0) {
+		t.Fatalf("bad 7m bars: %+v", bars)
+	}
+}
+
+func TestVolumeBars(t *testing.T) {
+	transactions := []Transaction{
+		{Minute: 570, Price: 1000, Volume: 3},
+		{Minute: 570, Price: 1000, Volume: 3},
+		{Minute: 571, Price: 1000, Volume: 5},
+		{Minute: 572, Price: 1000, Volume: 1},
+	}
+
+	err, bars := NewVolumeBarBuilder(500).Build(20181102, transactions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bars) != 3 || bars[0].Volume != 600 || bars[0].Date != barTime(20181102, 9, 30, 30) || bars[1].Volume != 500 || bars[2].Volume != 100 {
+		t.Fatalf("bad volume bars: %+v", bars)
+	}
+
+	err, bars = NewAmountBarBuilder(10000).Build(20181102, transactions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bars) != 2 || bars[0].Amount != 11000 || bars[1].Amount != 1000 {
+		t.Fatalf("bad amount bars: %+v", bars)
+	}
+
+	if err, _ := NewTimeBarBuilder(time.Millisecond).Build(20181102, transactions); err != ErrBadBarSize {
+		t.Fatalf("expect ErrBadBarSize, got %v", err)
+	}
+}
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a - b) <= tolerance * math.Max(1, math.Abs(b))
+}
+
+// 用历史成交生成的1分钟K线和服务器的1分钟K线对账，包括09:25集合竞价、午休和15:00收盘
+func TestBarsReconcile(t *testing.T) {
+	err, captures := LoadCaptures("testdata/bars_600000_20181105.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := createReplayAPI(t, captures)
+	defer api.Cleanup()
+
+	security := entity.ParseSecurityUnsafe("600000.SH")
+	day := uint32(20181105)
+	err, transactions := api.GetHistoryTransaction(security, day, 0, 2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err, records := api.GetMinuteData(security, 0, 240)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err, bars := NewTimeBarBuilder(time.Minute).Build(day, transactions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有成交的分钟不生成K线
+	expect := []entity.Record{}
+	for _, r := range records {
+		if r.Date >= tdxdatasource.DayDateToTimestamp(day) && r.Date < tdxdatasource.DayDateToTimestamp(day) + 86400000 && r.Volume > 0 {
+			expect = append(expect, r)
+		}
+	}
+	if len(expect) != 8 || len(bars) != len(expect) {
+		t.Fatalf("expect %d bars, got %d", len(expect), len(bars))
+	}
+	for i, e := range expect {
+		bar := bars[i]
+		if bar.Date != e.Date || !near(bar.Open, e.Open, 1e-3) || !near(bar.High, e.High, 1e-3) || !near(bar.Low, e.Low, 1e-3) ||
+			!near(bar.Close, e.Close, 1e-3) || !near(bar.Volume, e.Volume, 1e-6) || !near(bar.Amount, e.Amount, 1e-4) {
+			t.Fatalf("bar %d: expect %+v, got %+v", i, e, bar)
+		}
+	}
+	if expect[0].Date != barTime(day, 9, 31, 0) || expect[3].Date != barTime(day, 11, 30, 0) || expect[4].Date != barTime(day, 13, 1, 0) || expect[7].Date != barTime(day, 15, 0, 0) {
+		t.Fatalf("bad fixture: %+v", expect)
+	}
+}
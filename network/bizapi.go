@@ -1,8 +1,8 @@
 package network
 
 import (
+	"context"
 	"fmt"
-	"errors"
 	"io/ioutil"
 	"path/filepath"
 	"os"
@@ -17,8 +17,9 @@ import (
 )
 
 var blockExchangeMap = map[uint16]string{
-	0: "SZ",
-	1: "SH",
+	MARKET_SZ: "SZ",
+	MARKET_SH: "SH",
+	MARKET_BJ: "BJ",
 }
 
 type BizApi struct {
@@ -39,6 +40,33 @@ func CreateBizApi(host string) (error, *BizApi) {
 	return nil, result
 }
 
+// CreateBizApiWithOptions accepts hosts with or without port, the default port is 7709.
+func CreateBizApiWithOptions(options *Options) (error, *BizApi) {
+	result := &BizApi{workDir: "temp"}
+
+	withPortOptions := *options
+	withPortOptions.Hosts = make([]string, len(options.Hosts))
+	for i, host := range options.Hosts {
+		withPortOptions.Hosts[i] = WithDefaultPort(host)
+	}
+
+	err, api := CreateAPIWithOptions(&withPortOptions)
+	if err != nil {
+		return err, nil
+	}
+
+	result.api = api
+
+	return nil, result
+}
+
+// CreateBizApiWithHosts accepts hosts with or without port, the default port is 7709.
+func CreateBizApiWithHosts(hosts []string) (error, *BizApi) {
+	options := DefaultOptions()
+	options.Hosts = hosts
+	return CreateBizApiWithOptions(options)
+}
+
 func (this *BizApi) Cleanup() {
 	if this.api != nil {
 		this.api.Cleanup()
@@ -54,11 +82,41 @@ func (this *BizApi) SetTimeOut(timeout int) {
 	this.api.SetTimeOut(timeout)
 }
 
+func (this *BizApi) SetKeepAlive(interval time.Duration) {
+	this.api.SetKeepAlive(interval)
+}
+
+func (this *BizApi) PoolStats() PoolStats {
+	return this.api.PoolStats()
+}
+
+func (this *BizApi) SetRecorder(recorder *Recorder) {
+	this.api.SetRecorder(recorder)
+}
+
 func (this *BizApi) SetWorkDir(dir string) {
 	this.workDir = dir
 }
 
-func (this *BizApi) getStockCodesByBlock(block uint16) (error, []string) {
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// isUpdatedToday checks whether the cached file was written today
+func isUpdatedToday(filePath string) bool {
+	stats, err := os.Stat(filePath)
+	return err == nil && date.ToDayString(stats.ModTime()) >= date.GetTodayString()
+}
+
+func (this *BizApi) getStockCodesByBlock(ctx context.Context, block uint16) (error, []string) {
 	exchange, ok := blockExchangeMap[block]
 	if !ok {
 		return nil, nil
@@ -68,12 +126,8 @@ func (this *BizApi) getStockCodesByBlock(block uint16) (error, []string) {
 	zhbFile := "zhb.zip"
 
 	zhbFilePath := filepath.Join(outputDir, zhbFile)
-	stats, err := os.Stat(zhbFilePath)
-
-	today := date.GetTodayString()
-
-	if os.IsNotExist(err) || date.ToDayString(stats.ModTime()) < today {
-		err := this.DownloadFile(zhbFile, outputDir)
+	if !isUpdatedToday(zhbFilePath) {
+		err := this.DownloadFileContext(ctx, zhbFile, outputDir)
 		if err != nil {
 			return err, nil
 		}
@@ -88,24 +142,36 @@ func (this *BizApi) getStockCodesByBlock(block uint16) (error, []string) {
 }
 
 func (this *BizApi) GetSZStockCodes() (error, []string) {
-	return this.getStockCodesByBlock(0)
+	return this.GetSZStockCodesContext(context.Background())
+}
+
+func (this *BizApi) GetSZStockCodesContext(ctx context.Context) (error, []string) {
+	return this.getStockCodesByBlock(ctx, 0)
 }
 
 func (this *BizApi) GetSHStockCodes() (error, []string) {
-	return this.getStockCodesByBlock(1)
+	return this.GetSHStockCodesContext(context.Background())
+}
+
+func (this *BizApi) GetSHStockCodesContext(ctx context.Context) (error, []string) {
+	return this.getStockCodesByBlock(ctx, 1)
 }
 
 func (this *BizApi) GetAStockCodes() (error, []string) {
+	return this.GetAStockCodesContext(context.Background())
+}
+
+func (this *BizApi) GetAStockCodesContext(ctx context.Context) (error, []string) {
 	result := []string{}
 
-	err, codes := this.GetSZStockCodes()
+	err, codes := this.GetSZStockCodesContext(ctx)
 	if err != nil {
 		return err, nil
 	}
 
 	result = append(result, codes...)
 
-	err, codes = this.GetSHStockCodes()
+	err, codes = this.GetSHStockCodesContext(ctx)
 	if err != nil {
 		return err, nil
 	}
@@ -115,6 +181,10 @@ func (this *BizApi) GetAStockCodes() (error, []string) {
 }
 
 func (this *BizApi) GetInfoEx(securities []*entity.Security) (error, map[string][]*InfoExItem) {
+	return this.GetInfoExContext(context.Background(), securities)
+}
+
+func (this *BizApi) GetInfoExContext(ctx context.Context, securities []*entity.Security) (error, map[string][]*InfoExItem) {
 	result := map[string][]*InfoExItem{}
 
 	n := 20
@@ -124,7 +194,7 @@ func (this *BizApi) GetInfoEx(securities []*entity.Security) (error, map[string]
 			end = len(securities)
 		}
 		subCodes := securities[i:end]
-		err, infoEx := this.api.GetInfoEx(subCodes)
+		err, infoEx := this.api.GetInfoExContext(ctx, subCodes)
 		if err != nil {
 			return err, nil
 		}
@@ -137,7 +207,37 @@ func (this *BizApi) GetInfoEx(securities []*entity.Security) (error, map[string]
 	return nil, result
 }
 
+func (this *BizApi) GetInfoExEvents(securities []*entity.Security) (error, map[string][]*InfoExEvent) {
+	return this.GetInfoExEventsContext(context.Background(), securities)
+}
+
+func (this *BizApi) GetInfoExEventsContext(ctx context.Context, securities []*entity.Security) (error, map[string][]*InfoExEvent) {
+	result := map[string][]*InfoExEvent{}
+
+	n := 20
+	for i := 0; i < len(securities); i += n {
+		end := i + n
+		if end > len(securities) {
+			end = len(securities)
+		}
+		err, events := this.api.GetInfoExEventsContext(ctx, securities[i:end])
+		if err != nil {
+			return err, nil
+		}
+
+		for k, v := range events {
+			result[k] = v
+		}
+	}
+
+	return nil, result
+}
+
 func (this *BizApi) GetBid(securities []*entity.Security) (error, map[string]*Bid) {
+	return this.GetBidContext(context.Background(), securities)
+}
+
+func (this *BizApi) GetBidContext(ctx context.Context, securities []*entity.Security) (error, map[string]*Bid) {
 	result := map[string]*Bid{}
 
 	n := 20
@@ -147,7 +247,7 @@ func (this *BizApi) GetBid(securities []*entity.Security) (error, map[string]*Bi
 			end = len(securities)
 		}
 		subSecurities := securities[i:end]
-		err, bids := this.api.GetBid(subSecurities)
+		err, bids := this.api.GetBidContext(ctx, subSecurities)
 		if err != nil {
 			return err, nil
 		}
@@ -161,15 +261,55 @@ func (this *BizApi) GetBid(securities []*entity.Security) (error, map[string]*Bi
 }
 
 func (this *BizApi) GetInstantTransaction(security *entity.Security, offset, count uint16) (error, []Transaction) {
-	return this.api.GetInstantTransaction(security, offset, count)
+	return this.GetInstantTransactionContext(context.Background(), security, offset, count)
+}
+
+func (this *BizApi) GetInstantTransactionContext(ctx context.Context, security *entity.Security, offset, count uint16) (error, []Transaction) {
+	return this.api.GetInstantTransactionContext(ctx, security, offset, count)
 }
 
 func (this *BizApi) GetHistoryTransaction(security *entity.Security, date uint32, offset, count uint16) (error, []Transaction) {
-	return this.api.GetHistoryTransaction(security, date, offset, count)
+	return this.GetHistoryTransactionContext(context.Background(), security, date, offset, count)
+}
+
+func (this *BizApi) GetHistoryTransactionContext(ctx context.Context, security *entity.Security, date uint32, offset, count uint16) (error, []Transaction) {
+	return this.api.GetHistoryTransactionContext(ctx, security, date, offset, count)
+}
+
+func (this *BizApi) GetIndexPeriodData(security *entity.Security, period Period, offset, count uint16) (error, []IndexRecord) {
+	return this.GetIndexPeriodDataContext(context.Background(), security, period, offset, count)
+}
+
+func (this *BizApi) GetIndexPeriodDataContext(ctx context.Context, security *entity.Security, period Period, offset, count uint16) (error, []IndexRecord) {
+	err, uPeriod := GetTdxPeriod(period)
+	if err != nil {
+		return err, nil
+	}
+	return this.api.GetIndexPeriodDataContext(ctx, security, uPeriod, offset, count)
+}
+
+func (this *BizApi) GetMinuteTimeData(security *entity.Security) (error, []MinuteTimePoint) {
+	return this.GetMinuteTimeDataContext(context.Background(), security)
+}
+
+func (this *BizApi) GetMinuteTimeDataContext(ctx context.Context, security *entity.Security) (error, []MinuteTimePoint) {
+	return this.api.GetMinuteTimeDataContext(ctx, security)
+}
+
+func (this *BizApi) GetHistoryMinuteTimeData(security *entity.Security, date uint32) (error, []MinuteTimePoint) {
+	return this.GetHistoryMinuteTimeDataContext(context.Background(), security, date)
+}
+
+func (this *BizApi) GetHistoryMinuteTimeDataContext(ctx context.Context, security *entity.Security, date uint32) (error, []MinuteTimePoint) {
+	return this.api.GetHistoryMinuteTimeDataContext(ctx, security, date)
 }
 
 func (this *BizApi) DownloadInfoEx() error {
-	err, codes := this.GetAStockCodes()
+	return this.DownloadInfoExContext(context.Background())
+}
+
+func (this *BizApi) DownloadInfoExContext(ctx context.Context) error {
+	err, codes := this.GetAStockCodesContext(ctx)
 	if err != nil {
 		return err
 	}
@@ -178,7 +318,7 @@ func (this *BizApi) DownloadInfoEx() error {
 		securities[i] = entity.ParseSecurityUnsafe(code)
 	}
 
-	err, result := this.GetInfoEx(securities)
+	err, result := this.GetInfoExContext(ctx, securities)
 	if err != nil {
 		return err
 	}
@@ -199,6 +339,10 @@ func (this *BizApi) DownloadInfoEx() error {
 }
 
 func (this *BizApi) GetFinance(securites []*entity.Security) (error, map[string]*Finance) {
+	return this.GetFinanceContext(context.Background(), securites)
+}
+
+func (this *BizApi) GetFinanceContext(ctx context.Context, securites []*entity.Security) (error, map[string]*Finance) {
 	result := map[string]*Finance{}
 
 	n := 100
@@ -208,7 +352,7 @@ func (this *BizApi) GetFinance(securites []*entity.Security) (error, map[string]
 			end = len(securites)
 		}
 		subCodes := securites[i:end]
-		err, finances := this.api.GetFinance(subCodes)
+		err, finances := this.api.GetFinanceContext(ctx, subCodes)
 		if err != nil {
 			return err, nil
 		}
@@ -222,17 +366,13 @@ func (this *BizApi) GetFinance(securites []*entity.Security) (error, map[string]
 }
 
 func (this *BizApi) GetLatestPeriodData(security *entity.Security, period Period, offset int, count int) (error, []entity.Record) {
-	var uPeriod uint16
-	pName := period.ShortName()
-	switch {
-	case pName == "M1":
-		uPeriod = PERIOD_MINUTE
-	case pName == "M5":
-		uPeriod = PERIOD_MINUTE5
-	case pName == "D1":
-		uPeriod = PERIOD_DAY
-	default:
-		return errors.New("bad period"), nil
+	return this.GetLatestPeriodDataContext(context.Background(), security, period, offset, count)
+}
+
+func (this *BizApi) GetLatestPeriodDataContext(ctx context.Context, security *entity.Security, period Period, offset int, count int) (error, []entity.Record) {
+	err, uPeriod := GetTdxPeriod(period)
+	if err != nil {
+		return err, nil
 	}
 
 	result := []entity.Record{}
@@ -245,7 +385,7 @@ func (this *BizApi) GetLatestPeriodData(security *entity.Security, period Period
 			c = count - n
 		}
 
-		err, data := this.api.GetPeriodData(security, uPeriod, uint16(offset + n), uint16(c))
+		err, data := this.api.GetPeriodDataContext(ctx, security, uPeriod, uint16(offset + n), uint16(c))
 		if err != nil {
 			return err, nil
 		}
@@ -262,15 +402,76 @@ func (this *BizApi) GetLatestPeriodData(security *entity.Security, period Period
 }
 
 func (this *BizApi) GetLatestMinuteData(security *entity.Security, offset int, count int) (error, []entity.Record) {
-	return this.GetLatestPeriodData(security, PERIOD_M, offset, count)
+	return this.GetLatestMinuteDataContext(context.Background(), security, offset, count)
+}
+
+func (this *BizApi) GetLatestMinuteDataContext(ctx context.Context, security *entity.Security, offset int, count int) (error, []entity.Record) {
+	return this.GetLatestPeriodDataContext(ctx, security, PERIOD_M, offset, count)
 }
 
 func (this *BizApi) GetLatestDayData(security *entity.Security, count int) (error, []entity.Record) {
-	return this.GetLatestPeriodData(security, PERIOD_D, 0, count)
+	return this.GetLatestDayDataContext(context.Background(), security, count)
+}
+
+func (this *BizApi) GetLatestDayDataContext(ctx context.Context, security *entity.Security, count int) (error, []entity.Record) {
+	return this.GetLatestPeriodDataContext(ctx, security, PERIOD_D, 0, count)
+}
+
+func (this *BizApi) GetAdjustedPeriodData(security *entity.Security, period Period, offset int, count int, adjustType int) (error, []entity.Record) {
+	return this.GetAdjustedPeriodDataContext(context.Background(), security, period, offset, count, adjustType)
+}
+
+// GetAdjustedPeriodDataContext adjusts the records with the latest InfoEx. The factors are computed from
+// the daily records since listing, so the adjusted prices do not depend on offset and count.
+func (this *BizApi) GetAdjustedPeriodDataContext(ctx context.Context, security *entity.Security, period Period, offset int, count int, adjustType int) (error, []entity.Record) {
+	switch adjustType {
+	case ADJUST_NONE:
+		return this.GetLatestPeriodDataContext(ctx, security, period, offset, count)
+	case ADJUST_FORWARD, ADJUST_BACKWARD:
+	default:
+		return ErrBadAdjustType, nil
+	}
+
+	err, factors := this.GetAdjustFactorsContext(ctx, security)
+	if err != nil {
+		return err, nil
+	}
+
+	err, records := this.GetLatestPeriodDataContext(ctx, security, period, offset, count)
+	if err != nil {
+		return err, nil
+	}
+	return AdjustRecords(records, factors, adjustType)
+}
+
+func (this *BizApi) GetAdjustFactors(security *entity.Security) (error, []AdjustFactor) {
+	return this.GetAdjustFactorsContext(context.Background(), security)
+}
+
+// GetAdjustFactorsContext fetches the InfoEx and, if there are items, all the daily records since listing.
+func (this *BizApi) GetAdjustFactorsContext(ctx context.Context, security *entity.Security) (error, []AdjustFactor) {
+	err, infoEx := this.api.GetInfoExContext(ctx, []*entity.Security{security})
+	if err != nil {
+		return err, nil
+	}
+	items := infoEx[security.String()]
+	if len(items) == 0 {
+		return nil, []AdjustFactor{}
+	}
+
+	err, days := this.GetLatestPeriodDataContext(ctx, security, PERIOD_D, 0, 0xffff)
+	if err != nil {
+		return err, nil
+	}
+	return nil, GetAdjustFactors(days, items)
 }
 
 func (this *BizApi) DownloadFile(fileName string, outputDir string) error {
-	err, length := this.api.GetFileLength(fileName)
+	return this.DownloadFileContext(context.Background(), fileName, outputDir)
+}
+
+func (this *BizApi) DownloadFileContext(ctx context.Context, fileName string, outputDir string) error {
+	err, length := this.api.GetFileLengthContext(ctx, fileName)
 	if err != nil {
 		return err
 	}
@@ -283,11 +484,14 @@ func (this *BizApi) DownloadFile(fileName string, outputDir string) error {
 	var getPacket = func() (error error, packetLength uint32, data []byte) {
 		retryTimes := 0
 		for retryTimes < 3 {
-			err, packetLength, data = this.api.GetFileData(fileName, offset, count)
-			if err == nil {
+			err, packetLength, data = this.api.GetFileDataContext(ctx, fileName, offset, count)
+			if err == nil || !IsRetryable(err) {
+				return
+			}
+			if err1 := sleepContext(ctx, time.Millisecond * 500); err1 != nil {
+				error = err1
 				return
 			}
-			time.Sleep(time.Millisecond * 500)
 			retryTimes++
 		}
 		return
@@ -299,7 +503,7 @@ func (this *BizApi) DownloadFile(fileName string, outputDir string) error {
 			return err
 		}
 		if packetLength != uint32(len(data)) {
-			return errors.New("bad data")
+			return &ProtocolError{Cmd: CMD_GET_FILE_DATA, Err: ErrBadData, Data: data}
 		}
 
 		copy(fileData[offset:offset + packetLength], data[:])
@@ -313,7 +517,11 @@ func (this *BizApi) DownloadFile(fileName string, outputDir string) error {
 }
 
 func (this *BizApi) GetNamesData(block uint16) (err error, namesData []byte) {
-	err, total := this.api.GetNamesLength(block)
+	return this.GetNamesDataContext(context.Background(), block)
+}
+
+func (this *BizApi) GetNamesDataContext(ctx context.Context, block uint16) (err error, namesData []byte) {
+	err, total := this.api.GetNamesLengthContext(ctx, block)
 	if err != nil {
 		return
 	}
@@ -321,11 +529,14 @@ func (this *BizApi) GetNamesData(block uint16) (err error, namesData []byte) {
 	var getPacket = func(offset uint32) (err error, packetLength uint16, data []byte) {
 		retryTimes := 0
 		for retryTimes < 3 {
-			err, packetLength, data = this.api.GetNamesData(block, uint16(offset))
-			if err == nil {
+			err, packetLength, data = this.api.GetNamesDataContext(ctx, block, uint16(offset))
+			if err == nil || !IsRetryable(err) {
+				return
+			}
+			if err1 := sleepContext(ctx, time.Millisecond * 500); err1 != nil {
+				err = err1
 				return
 			}
-			time.Sleep(time.Millisecond * 500)
 			retryTimes++
 		}
 		return
@@ -341,6 +552,12 @@ func (this *BizApi) GetNamesData(block uint16) (err error, namesData []byte) {
 			return
 		}
 
+		// 没有数据时offset不会增加
+		if packetLength == 0 {
+			err = &ProtocolError{Cmd: CMD_NAMES, Err: ErrIncompleteData, Data: data}
+			return
+		}
+
 		namesData = append(namesData, data...)
 
 		offset += uint32(packetLength)
@@ -349,7 +566,46 @@ func (this *BizApi) GetNamesData(block uint16) (err error, namesData []byte) {
 	return
 }
 
+func (this *BizApi) GetSecurityList(market uint16) (error, []*SecurityInfo) {
+	return this.GetSecurityListContext(context.Background(), market)
+}
+
+// GetSecurityListContext downloads all the securities of market page by page.
+func (this *BizApi) GetSecurityListContext(ctx context.Context, market uint16) (error, []*SecurityInfo) {
+	err, data := this.GetNamesDataContext(ctx, market)
+	if err != nil {
+		return err, nil
+	}
+
+	err, result := ParseSecurityList(market, data)
+	if err != nil {
+		return &ProtocolError{Cmd: CMD_NAMES, Data: data, Err: err}, nil
+	}
+	return nil, result
+}
+
+func (this *BizApi) GetAllSecurityList() (error, []*SecurityInfo) {
+	return this.GetAllSecurityListContext(context.Background())
+}
+
+// GetAllSecurityListContext enumerates the securities of SZ, SH and BJ without downloading zhb.zip.
+func (this *BizApi) GetAllSecurityListContext(ctx context.Context) (error, []*SecurityInfo) {
+	var result []*SecurityInfo
+	for _, market := range []uint16{MARKET_SZ, MARKET_SH, MARKET_BJ} {
+		err, infos := this.GetSecurityListContext(ctx, market)
+		if err != nil {
+			return err, nil
+		}
+		result = append(result, infos...)
+	}
+	return nil, result
+}
+
 func (this *BizApi) DownloadNamesData(blocks []uint16) error {
+	return this.DownloadNamesDataContext(context.Background(), blocks)
+}
+
+func (this *BizApi) DownloadNamesDataContext(ctx context.Context, blocks []uint16) error {
 	if len(blocks) == 0 {
 		return nil
 	}
@@ -359,120 +615,159 @@ func (this *BizApi) DownloadNamesData(blocks []uint16) error {
 	os.MkdirAll(outputDir, 0777)
 
 	for _, block := range blocks {
-		err, data := this.GetNamesData(block)
+		err, data := this.GetNamesDataContext(ctx, block)
 		if err != nil {
 			return err
 		}
-		filePath := filepath.Join(outputDir, fmt.Sprintf("%s-names.dat", strings.ToLower(blockExchangeMap[block])))
-
-		ioutil.WriteFile(filePath, data, 0666)
+		ioutil.WriteFile(this.getNamesFilePath(block), data, 0666)
 	}
 
 	return nil
 }
 
-func (this *BizApi) DownloadAStockNamesData() error {
-	return this.DownloadNamesData([]uint16{0, 1})
+func (this *BizApi) getNamesFilePath(block uint16) string {
+	return filepath.Join(this.workDir, "T0002/hq_cache", fmt.Sprintf("%s-names.dat", strings.ToLower(blockExchangeMap[block])))
 }
 
-func (this BizApi) DownloadPeriodHisDataAsync(security *entity.Security, period Period, startDate, endDate uint32) (chan<- bool, <-chan error) {
-	cancelCh := make(chan bool, 1) // 避免阻塞
-	retCh := make(chan error)
+func (this *BizApi) GetNames(block uint16) (error, []*SecurityInfo) {
+	return this.GetNamesContext(context.Background(), block)
+}
 
-	go func() {
-		if startDate == 0 {
-			startDate = 19900101
+// GetNamesContext decodes the names data of block, the names file is downloaded at most once a day.
+func (this *BizApi) GetNamesContext(ctx context.Context, block uint16) (error, []*SecurityInfo) {
+	if _, ok := blockExchangeMap[block]; !ok {
+		return ErrUnknownBlock, nil
+	}
+
+	filePath := this.getNamesFilePath(block)
+	if !isUpdatedToday(filePath) {
+		err := this.DownloadNamesDataContext(ctx, []uint16{block})
+		if err != nil {
+			return err, nil
 		}
+	}
+
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return err, nil
+	}
+	return ParseSecurityList(block, data)
+}
+
+func (this *BizApi) DownloadAStockNamesData() error {
+	return this.DownloadAStockNamesDataContext(context.Background())
+}
+
+func (this *BizApi) DownloadAStockNamesDataContext(ctx context.Context) error {
+	return this.DownloadNamesDataContext(ctx, []uint16{0, 1})
+}
 
-		if endDate == 0 {
-			endDate = uint32(date.GetTodayInt())
+func (this BizApi) DownloadPeriodHisDataContext(ctx context.Context, security *entity.Security, period Period, startDate, endDate uint32) error {
+	if startDate == 0 {
+		startDate = 19900101
+	}
+
+	if endDate == 0 {
+		endDate = uint32(date.GetTodayInt())
+	}
+
+	// Calculate all days for segmentation
+	startTs := tdxdatasource.DayDateToTimestamp(startDate)
+	endTs := tdxdatasource.DayDateToTimestamp(endDate)
+	if startTs > endTs {
+		return nil
+	}
+	const dayMillis = 24 * 60 * 60 * 1000
+	nDays := (endTs - startTs) / dayMillis + 1
+
+	days := make([]uint32, nDays)
+	for i, ts := 0, startTs; ts <= endTs; i, ts = i+1, ts+dayMillis {
+		days[i] = tdxdatasource.TimestampToDayDate(ts)
+	}
+
+	err, uPeriod := GetTdxPeriod(period)
+	if err != nil {
+		return err
+	}
+
+	// 分钟数据每次取一天
+	step := 100
+	if isIntradayPeriod(uPeriod) {
+		step = 1
+	}
+
+	var getPacket = func(from, to uint32) (err error, data []byte) {
+		retryTimes := 0
+		for retryTimes < 3 {
+			err, data = this.api.GetPeriodHisDataContext(ctx, security, uPeriod, from, to)
+			if err == nil || !IsRetryable(err) {
+				return
+			}
+			if err1 := sleepContext(ctx, time.Millisecond * 500); err1 != nil {
+				err = err1
+				return
+			}
+			retryTimes++
 		}
+		return
+	}
 
-		// Calculate all days for segmentation
-		startTs := tdxdatasource.DayDateToTimestamp(startDate)
-		endTs := tdxdatasource.DayDateToTimestamp(endDate)
-		if startTs > endTs {
-			retCh <- nil
-			close(retCh)
-			return
+	// Get data now
+	ds := tdxdatasource.NewDataSource(this.workDir, true)
+
+	for i := 0; i < len(days); i += step {
+		if err := ctx.Err(); err != nil {
+			return err
 		}
-		const dayMillis = 24 * 60 * 60 * 1000
-		nDays := (endTs - startTs) / dayMillis + 1
-
-		days := make([]uint32, nDays)
-		for i, ts := 0, startTs; ts <= endTs; i, ts = i+1, ts+dayMillis {
-			days[i] = tdxdatasource.TimestampToDayDate(ts)
-		}
-
-		var step int
-		var uPeriod uint16
-		pName := period.ShortName()
-		switch {
-		case pName == "M1":
-			step = 1
-			uPeriod = PERIOD_MINUTE
-		case pName == "M5":
-			step = 1
-			uPeriod = PERIOD_MINUTE5
-		case pName == "D1":
-			step = 100
-			uPeriod = PERIOD_DAY
-		default:
-			retCh <- errors.New("bad period")
-			return
+
+		from := days[i]
+		var to uint32
+		if i + step > len(days) {
+			to = endDate
+		} else {
+			to = days[i + step - 1]
 		}
 
-		var getPacket = func(from, to uint32) (err error, data []byte) {
-			retryTimes := 0
-			for retryTimes < 3 {
-				err, data = this.api.GetPeriodHisData(security, uPeriod, from, to)
-				if err == nil {
-					return
-				}
-				time.Sleep(time.Millisecond * 500)
-				retryTimes++
-			}
-			return
+		err, data := getPacket(from, to)
+		if err != nil {
+			return err
 		}
 
-		// Get data now
-		ds := tdxdatasource.NewDataSource(this.workDir, true)
+		if len(data) == 0 {
+			continue
+		}
 
-		loop:
-		for i := 0; i < len(days); i += step {
-			select {
-			case <- cancelCh:
-				break loop
-			default:
-				break
-			}
+		err = ds.AppendRawData(security, period, data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-			from := days[i]
-			var to uint32
-			if i + step > len(days) {
-				to = endDate
-			} else {
-				to = days[i + step - 1]
-			}
+// DownloadPeriodHisDataAsync is kept for compatibility, prefer DownloadPeriodHisDataContext.
+func (this BizApi) DownloadPeriodHisDataAsync(security *entity.Security, period Period, startDate, endDate uint32) (chan<- bool, <-chan error) {
+	cancelCh := make(chan bool, 1) // 避免阻塞
+	retCh := make(chan error)
 
-			err, data := getPacket(from, to)
-			if err != nil {
-				retCh <- err
-				goto ret
-			}
+	ctx, cancel := context.WithCancel(context.Background())
 
-			if len(data) == 0 {
-				continue
-			}
+	go func() {
+		select {
+		case <- cancelCh:
+			cancel()
+		case <- ctx.Done():
+		}
+	}()
 
-			err = ds.AppendRawData(security, period, data)
-			if err != nil {
-				retCh <- err
-				goto ret
-			}
+	go func() {
+		err := this.DownloadPeriodHisDataContext(ctx, security, period, startDate, endDate)
+		if err == context.Canceled {
+			// Cancelled by caller
+			err = nil
 		}
-		retCh <- nil
-		ret:
+		cancel()
+		retCh <- err
 		close(retCh)
 	}()
 
@@ -480,13 +775,14 @@ func (this BizApi) DownloadPeriodHisDataAsync(security *entity.Security, period
 }
 
 func (this BizApi) DownloadPeriodHisData(security *entity.Security, period Period, startDate, endDate uint32) error {
-	cancelCh, retCh := this.DownloadPeriodHisDataAsync(security, period, startDate, endDate)
-	err := <- retCh
-	close(cancelCh)
-	return err
+	return this.DownloadPeriodHisDataContext(context.Background(), security, period, startDate, endDate)
 }
 
 func (this BizApi) DownloadLatestPeriodHisData(security *entity.Security, period Period) error {
+	return this.DownloadLatestPeriodHisDataContext(context.Background(), security, period)
+}
+
+func (this BizApi) DownloadLatestPeriodHisDataContext(ctx context.Context, security *entity.Security, period Period) error {
 	ds := tdxdatasource.NewDataSource(this.workDir, true)
 	err, r := ds.GetLastRecord(security, period)
 	var startDate, endDate uint32
@@ -495,7 +791,7 @@ func (this BizApi) DownloadLatestPeriodHisData(security *entity.Security, period
 		startDate = uint32(date.GetDateDay(r.Date))
 	}
 
-	return this.DownloadPeriodHisData(security, period, startDate, endDate)
+	return this.DownloadPeriodHisDataContext(ctx, security, period, startDate, endDate)
 }
 
 func (this BizApi) DownloadLatestPeriodHisDataAsync(security *entity.Security, period Period, startDate, endDate uint32) (chan<- bool, <-chan error) {
@@ -507,4 +803,4 @@ func (this BizApi) DownloadLatestPeriodHisDataAsync(security *entity.Security, p
 	}
 
 	return this.DownloadPeriodHisDataAsync(security, period, startDate, endDate)
-}
\ No newline at end of file
+}
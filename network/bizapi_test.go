@@ -1,6 +1,9 @@
 package network
 
 import (
+	"bytes"
+	"encoding/hex"
+	"errors"
 	"testing"
 	"github.com/stephenlyu/tds/entity"
 	"log"
@@ -29,3 +32,34 @@ func TestBizApi_GetInstantTransaction(t *testing.T) {
 		fmt.Printf("%+v\n", &t)
 	}
 }
+
+func TestBizApi_GetNamesUnknownBlock(t *testing.T) {
+	api := &BizApi{workDir: t.TempDir()}
+	if err, infos := api.GetNames(99); !errors.Is(err, ErrUnknownBlock) || infos != nil {
+		t.Fatalf("expect ErrUnknownBlock, got %v", err)
+	}
+}
+
+// 服务器返回的记录数为0时不能死循环
+func TestBizApi_GetNamesDataEmptyPage(t *testing.T) {
+	captures := []*Capture{}
+	for _, req := range []WritableRequest{NewNamesLenReq(1, 0), NewNamesReq(2, 0, 0)} {
+		buf := new(bytes.Buffer)
+		req.Write(buf)
+
+		body := EncodeNamesLenData(100)
+		if req.GetCmd() == CMD_NAMES {
+			body = EncodeNamesData(0, nil)
+		}
+		captures = append(captures, &Capture{Cmd: req.GetCmd(), SeqId: req.GetSeqId(), Req: hex.EncodeToString(buf.Bytes()), Resp: hex.EncodeToString(EncodeResp(req.GetSeqId(), req.GetCmd(), body, false))})
+	}
+
+	api := &BizApi{api: createReplayAPI(t, captures)}
+	defer api.api.Cleanup()
+
+	err, data := api.GetNamesData(0)
+	var protocolErr *ProtocolError
+	if !errors.As(err, &protocolErr) || protocolErr.Cmd != CMD_NAMES || data != nil {
+		t.Fatalf("expect ProtocolError, got %v", err)
+	}
+}
@@ -0,0 +1,277 @@
+package network
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+	"bufio"
+	"bytes"
+)
+
+const REQ_HEADER_LEN = 10			// Zip, SeqId, PacketType, Len, Len1
+
+var ErrNoCapture = errors.New("no capture for request")
+
+// Capture is one request/response pair, a capture file contains one json object per line.
+type Capture struct {
+	Time time.Time		`json:"time"`
+	Cmd uint16			`json:"cmd"`
+	SeqId uint32		`json:"seq_id"`
+	Req string			`json:"req"`				// hex
+	Resp string			`json:"resp,omitempty"`	// hex
+	Error string		`json:"error,omitempty"`
+}
+
+// captureKey identifies a request regardless of its seq id
+func captureKey(req []byte) string {
+	if len(req) < 5 {
+		return hex.EncodeToString(req)
+	}
+	key := make([]byte, len(req))
+	copy(key, req)
+	for i := 1; i < 5; i++ {
+		key[i] = 0
+	}
+	return hex.EncodeToString(key)
+}
+
+type Recorder struct {
+	lock sync.Mutex
+	w io.Writer
+	closer io.Closer
+}
+
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{w: w}
+}
+
+// CreateRecorder creates the capture file at path.
+func CreateRecorder(path string) (error, *Recorder) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err, nil
+	}
+	return nil, &Recorder{w: f, closer: f}
+}
+
+func (this *Recorder) Record(req []byte, resp []byte, err error) error {
+	capture := &Capture{
+		Time: time.Now(),
+		Req: hex.EncodeToString(req),
+		Resp: hex.EncodeToString(resp),
+	}
+	if len(req) >= REQ_HEADER_LEN + 2 {
+		capture.SeqId = binary.LittleEndian.Uint32(req[1:5])
+		capture.Cmd = binary.LittleEndian.Uint16(req[REQ_HEADER_LEN:REQ_HEADER_LEN + 2])
+	}
+	if err != nil {
+		capture.Error = err.Error()
+	}
+
+	line, err := json.Marshal(capture)
+	if err != nil {
+		return err
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	_, err = this.w.Write(append(line, '\n'))
+	return err
+}
+
+func (this *Recorder) Close() error {
+	if this.closer == nil {
+		return nil
+	}
+	return this.closer.Close()
+}
+
+func ReadCaptures(r io.Reader) (error, []*Capture) {
+	result := []*Capture{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64 * 1024), 1024 * 1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		capture := &Capture{}
+		if err := json.Unmarshal(line, capture); err != nil {
+			return err, nil
+		}
+		result = append(result, capture)
+	}
+
+	return scanner.Err(), result
+}
+
+func LoadCaptures(path string) (error, []*Capture) {
+	f, err := os.Open(path)
+	if err != nil {
+		return err, nil
+	}
+	defer f.Close()
+
+	return ReadCaptures(f)
+}
+
+// Offsets of the date fields which are just today, so the captures of another day can be used.
+// Dates selecting the data, e.g. HisTransReq.Date, must match exactly.
+var captureDateOffsets = map[uint16]int{
+	CMD_NAMES_LEN: REQ_HEADER_LEN + 4,			// NamesLenReq.Date
+}
+
+// looseKey identifies a request regardless of its seq id and date, empty for the requests without date.
+func looseKey(req []byte) string {
+	if len(req) < REQ_HEADER_LEN + 2 {
+		return ""
+	}
+	offset, ok := captureDateOffsets[binary.LittleEndian.Uint16(req[REQ_HEADER_LEN:])]
+	if !ok || len(req) < offset + 4 {
+		return ""
+	}
+
+	key := make([]byte, len(req))
+	copy(key, req)
+	for i := offset; i < offset + 4; i++ {
+		key[i] = 0
+	}
+	return captureKey(key)
+}
+
+// ReplayDialer serves recorded captures back instead of connecting to a server.
+// Requests are matched by their bytes except the seq id, identical requests get their
+// responses in the recorded order, the last one is repeated after that.
+// If nothing matches, a NamesLenReq capture of another day is used, otherwise ErrNoCapture is returned.
+type ReplayDialer struct {
+	lock sync.Mutex
+	queues map[string][]*Capture
+	looseQueues map[string][]*Capture
+}
+
+func NewReplayDialer(captures []*Capture) *ReplayDialer {
+	result := &ReplayDialer{queues: map[string][]*Capture{}, looseQueues: map[string][]*Capture{}}
+	for _, capture := range captures {
+		if capture.Error != "" || capture.Resp == "" {
+			continue
+		}
+		req, err := hex.DecodeString(capture.Req)
+		if err != nil {
+			continue
+		}
+		key := captureKey(req)
+		result.queues[key] = append(result.queues[key], capture)
+		if key = looseKey(req); key != "" {
+			result.looseQueues[key] = append(result.looseQueues[key], capture)
+		}
+	}
+	return result
+}
+
+func (this *ReplayDialer) pop(queues map[string][]*Capture, key string) *Capture {
+	queue := queues[key]
+	if len(queue) == 0 {
+		return nil
+	}
+	if len(queue) > 1 {
+		queues[key] = queue[1:]
+	}
+	return queue[0]
+}
+
+func (this *ReplayDialer) Dial(network, address string) (net.Conn, error) {
+	return this.DialContext(context.Background(), network, address)
+}
+
+func (this *ReplayDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	client, server := net.Pipe()
+	conn := &replayConn{Conn: client}
+	go this.serve(server, conn)
+	return conn, nil
+}
+
+func (this *ReplayDialer) next(req []byte) (error, []byte) {
+	// 连接建立时的握手包
+	for _, reqHex := range handshakeReqs {
+		if reqHex == hex.EncodeToString(req) {
+			return nil, EncodeResp(binary.LittleEndian.Uint32(req[1:5]), binary.LittleEndian.Uint16(req[REQ_HEADER_LEN:]), nil, false)
+		}
+	}
+
+	this.lock.Lock()
+	capture := this.pop(this.queues, captureKey(req))
+	if key := looseKey(req); capture == nil && key != "" {
+		capture = this.pop(this.looseQueues, key)
+	}
+	this.lock.Unlock()
+
+	if capture == nil {
+		return fmt.Errorf("%w, req: %x", ErrNoCapture, req), nil
+	}
+
+	resp, err := hex.DecodeString(capture.Resp)
+	if err != nil || len(resp) < RESP_HEADER_LEN {
+		return fmt.Errorf("%w, bad capture: %s", ErrNoCapture, capture.Resp), nil
+	}
+
+	// 使用请求的seq id
+	result := make([]byte, len(resp))
+	copy(result, resp)
+	copy(result[5:9], req[1:5])
+	return nil, result
+}
+
+func (this *ReplayDialer) serve(server net.Conn, conn *replayConn) {
+	defer server.Close()
+
+	for {
+		err, req := ReadReq(server)
+		if err != nil {
+			return
+		}
+
+		err, resp := this.next(req)
+		if err != nil {
+			conn.fail(err)
+			return
+		}
+
+		if _, err := server.Write(resp); err != nil {
+			return
+		}
+	}
+}
+
+type replayConn struct {
+	net.Conn
+	lock sync.Mutex
+	err error
+}
+
+func (this *replayConn) fail(err error) {
+	this.lock.Lock()
+	this.err = err
+	this.lock.Unlock()
+}
+
+func (this *replayConn) Read(b []byte) (int, error) {
+	n, err := this.Conn.Read(b)
+	if err != nil {
+		this.lock.Lock()
+		if this.err != nil {
+			err = this.err
+		}
+		this.lock.Unlock()
+	}
+	return n, err
+}
@@ -0,0 +1,136 @@
+package network
+
+import (
+	"testing"
+	"bytes"
+	"encoding/hex"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"github.com/stephenlyu/tds/entity"
+)
+
+func createReplayAPI(t *testing.T, captures []*Capture) *API {
+	options := DefaultOptions()
+	options.Hosts = []string{"replay:7709"}
+	options.InitialCap = 1
+	options.MaxCap = 1
+	options.Dialer = NewReplayDialer(captures)
+
+	err, api := CreateAPIWithOptions(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return api
+}
+
+func TestRecordReplay(t *testing.T) {
+	buf := new(bytes.Buffer)
+	NewNamesLenReq(100, 1).Write(buf)
+	req := buf.Bytes()
+
+	// 记录的请求日期和今天不同
+	binary.LittleEndian.PutUint32(req[len(req) - 4:], 20180101)
+
+	resp := buildResp(100, CMD_NAMES_LEN, []byte{0x10, 0x27})
+	captures := []*Capture{{Cmd: CMD_NAMES_LEN, SeqId: 100, Req: hex.EncodeToString(req), Resp: hex.EncodeToString(resp)}}
+
+	api := createReplayAPI(t, captures)
+	defer api.Cleanup()
+
+	record := new(bytes.Buffer)
+	api.SetRecorder(NewRecorder(record))
+
+	for i := 0; i < 2; i++ {
+		err, length := api.GetNamesLength(1)
+		if err != nil || length != 10000 {
+			t.Fatalf("bad result, err: %v length: %d", err, length)
+		}
+	}
+
+	err := api.HeartBeat()
+	if !errors.Is(err, ErrNoCapture) {
+		t.Fatalf("expect ErrNoCapture, got %v", err)
+	}
+
+	err, recorded := ReadCaptures(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded) != 3 {
+		t.Fatalf("expect 3 captures, got %d", len(recorded))
+	}
+	if recorded[0].Cmd != CMD_NAMES_LEN || recorded[0].Error != "" || recorded[2].Cmd != CMD_HEART_BEAT || recorded[2].Error == "" {
+		t.Fatalf("bad captures: %+v %+v", recorded[0], recorded[2])
+	}
+
+	// 回放刚才记录的数据
+	api1 := createReplayAPI(t, recorded)
+	defer api1.Cleanup()
+
+	err, length := api1.GetNamesLength(1)
+	if err != nil || length != 10000 {
+		t.Fatalf("bad result, err: %v length: %d", err, length)
+	}
+}
+
+// 只有NamesLenReq可以使用其它日期的记录，历史成交等按日期取数据的请求必须日期相同
+func TestReplayLooseMatch(t *testing.T) {
+	s1 := entity.ParseSecurityUnsafe("000001.SZ")
+	s2 := entity.ParseSecurityUnsafe("000002.SZ")
+
+	transactions := []Transaction{{Date: 20181102, Minute: 570, Price: 1050, Volume: 10, Count: 1, BS: BS_BUY}}
+	namesLenReq := NewNamesLenReq(3, 1)
+	namesLenReq.Date = 20181102
+	captures := []*Capture{}
+	for _, req := range []WritableRequest{NewHisTransReq(1, 20181102, s1, 0, 10), NewBidReq(2), namesLenReq} {
+		if bidReq, ok := req.(*BidReq); ok {
+			bidReq.AddCode(s1)
+		}
+		buf := new(bytes.Buffer)
+		req.Write(buf)
+
+		var body []byte
+		switch req.GetCmd() {
+		case CMD_HIS_TRANS:
+			body = EncodeHisTransData(transactions)
+		case CMD_NAMES_LEN:
+			body = EncodeNamesLenData(10000)
+		default:
+			body = EncodeBidData(map[string]*Bid{"000001.SZ": {StockCode: "000001.SZ", Close: 1050}})
+		}
+		captures = append(captures, &Capture{Cmd: req.GetCmd(), SeqId: req.GetSeqId(), Req: hex.EncodeToString(buf.Bytes()), Resp: hex.EncodeToString(EncodeResp(req.GetSeqId(), req.GetCmd(), body, false))})
+	}
+
+	api := createReplayAPI(t, captures)
+	defer api.Cleanup()
+
+	// NamesLenReq带的是当天日期
+	err, length := api.GetNamesLength(1)
+	if err != nil || length != 10000 {
+		t.Fatalf("names length of another day should match, err: %v length: %d", err, length)
+	}
+
+	err, result := api.GetHistoryTransaction(s1, 20181102, 0, 10)
+	if err != nil || !reflect.DeepEqual(result, transactions) {
+		t.Fatalf("bad transactions, err: %v result: %+v", err, result)
+	}
+
+	api1 := createReplayAPI(t, captures)
+	defer api1.Cleanup()
+	if err, _ := api1.GetHistoryTransaction(s1, 20181105, 0, 10); !errors.Is(err, ErrNoCapture) {
+		t.Fatalf("expect ErrNoCapture for another date, got %v", err)
+	}
+
+	api2 := createReplayAPI(t, captures)
+	defer api2.Cleanup()
+	if err, _ := api2.GetHistoryTransaction(s2, 20181102, 0, 10); !errors.Is(err, ErrNoCapture) {
+		t.Fatalf("expect ErrNoCapture for another security, got %v", err)
+	}
+
+	api3 := createReplayAPI(t, captures)
+	defer api3.Cleanup()
+	if err, _ := api3.GetBid([]*entity.Security{s2}); !errors.Is(err, ErrNoCapture) {
+		t.Fatalf("expect ErrNoCapture for another security, got %v", err)
+	}
+}
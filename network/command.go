@@ -0,0 +1,298 @@
+package network
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// WritableRequest is a request which can be sent by API.Do, its seq id is assigned by API.
+type WritableRequest interface {
+	Request
+	SetSeqId(seqId uint32)
+	Write(writer *bytes.Buffer)
+}
+
+// Command describes one TDX command.
+type Command struct {
+	Cmd uint16
+	Name string
+	DecodeReq func(parser *ReqParser, header Header) Request		// 从原始请求构建请求
+	DecodeResp func(req Request, data []byte) (error, interface{})
+	LogResp bool			// SetLogEnabled时把响应写入raw.dat
+}
+
+type FileData struct {
+	Length uint32
+	Data []byte
+}
+
+type NamesData struct {
+	Count uint16
+	Data []byte
+}
+
+var (
+	commandLock sync.RWMutex
+	commands = map[uint16]*Command{}
+)
+
+// RegisterCommand adds or replaces the command with the same cmd.
+func RegisterCommand(command *Command) {
+	commandLock.Lock()
+	defer commandLock.Unlock()
+	commands[command.Cmd] = command
+}
+
+// GetCommand returns nil if cmd is not registered.
+func GetCommand(cmd uint16) *Command {
+	commandLock.RLock()
+	defer commandLock.RUnlock()
+	return commands[cmd]
+}
+
+// Commands returns all registered commands ordered by cmd.
+func Commands() []*Command {
+	commandLock.RLock()
+	defer commandLock.RUnlock()
+
+	result := make([]*Command, 0, len(commands))
+	for _, command := range commands {
+		result = append(result, command)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Cmd < result[j].Cmd
+	})
+	return result
+}
+
+// GetCommandName returns the registered name, or the hex cmd for unknown commands.
+func GetCommandName(cmd uint16) string {
+	if command := GetCommand(cmd); command != nil {
+		return command.Name
+	}
+	return fmt.Sprintf("0x%04x", cmd)
+}
+
+func decodeRawReq(parser *ReqParser, header Header) Request {
+	return &RawReq{Header: header, Data: parser.getRemain()}
+}
+
+func decodeEmptyResp(req Request, data []byte) (error, interface{}) {
+	return NewHeartBeatParser(req, data).Parse(), nil
+}
+
+func init() {
+	RegisterCommand(&Command{
+		Cmd: CMD_HANDSHAKE1,
+		Name: "handshake1",
+		DecodeReq: decodeRawReq,
+		DecodeResp: decodeEmptyResp,
+	})
+	RegisterCommand(&Command{
+		Cmd: CMD_HANDSHAKE2,
+		Name: "handshake2",
+		DecodeReq: decodeRawReq,
+		DecodeResp: decodeEmptyResp,
+	})
+	RegisterCommand(&Command{
+		Cmd: CMD_HEART_BEAT,
+		Name: "heart_beat",
+		DecodeReq: func(parser *ReqParser, header Header) Request {
+			return &HeartBeatReq{Header: header}
+		},
+		DecodeResp: decodeEmptyResp,
+	})
+	RegisterCommand(&Command{
+		Cmd: CMD_INFO_EX,
+		Name: "info_ex",
+		DecodeReq: func(parser *ReqParser, header Header) Request {
+			req := &InfoExReq{Header: header, Stocks: parser.getStocks(0)}
+			req.Count = uint16(len(req.Stocks))
+			return req
+		},
+		DecodeResp: func(req Request, data []byte) (error, interface{}) {
+			return NewInfoExParser(req, data).ParseEvents()
+		},
+	})
+	RegisterCommand(&Command{
+		Cmd: CMD_FINANCE,
+		Name: "finance",
+		DecodeReq: func(parser *ReqParser, header Header) Request {
+			req := &FinanceReq{Header: header, Stocks: parser.getStocks(0)}
+			req.Count = uint16(len(req.Stocks))
+			return req
+		},
+		DecodeResp: func(req Request, data []byte) (error, interface{}) {
+			return NewFinanceParser(req, data).Parse()
+		},
+	})
+	RegisterCommand(&Command{
+		Cmd: CMD_BID,
+		Name: "bid",
+		DecodeReq: func(parser *ReqParser, header Header) Request {
+			req := &BidReq{Header: header, Stocks: parser.getStocks(4)}
+			req.Count = uint16(len(req.Stocks))
+			return req
+		},
+		DecodeResp: func(req Request, data []byte) (error, interface{}) {
+			return NewBidParser(req, data).Parse()
+		},
+		LogResp: true,
+	})
+	RegisterCommand(&Command{
+		Cmd: CMD_INSTANT_TRANS,
+		Name: "instant_trans",
+		DecodeReq: func(parser *ReqParser, header Header) Request {
+			return &InstantTransReq{
+				Header: header,
+				Location: parser.getUint16(),
+				StockCode: parser.getString(STOCK_CODE_LEN),
+				Offset: parser.getUint16(),
+				Count: parser.getUint16(),
+			}
+		},
+		DecodeResp: func(req Request, data []byte) (error, interface{}) {
+			return NewInstantTransParser(req, data).Parse()
+		},
+	})
+	RegisterCommand(&Command{
+		Cmd: CMD_HIS_TRANS,
+		Name: "his_trans",
+		DecodeReq: func(parser *ReqParser, header Header) Request {
+			return &HisTransReq{
+				Header: header,
+				Date: parser.getUint32(),
+				Location: parser.getUint16(),
+				StockCode: parser.getString(STOCK_CODE_LEN),
+				Offset: parser.getUint16(),
+				Count: parser.getUint16(),
+			}
+		},
+		DecodeResp: func(req Request, data []byte) (error, interface{}) {
+			return NewHisTransParser(req, data).Parse()
+		},
+	})
+	RegisterCommand(&Command{
+		Cmd: CMD_PERIOD_DATA,
+		Name: "period_data",
+		DecodeReq: func(parser *ReqParser, header Header) Request {
+			return &PeriodDataReq{
+				Header: header,
+				Location: parser.getUint16(),
+				StockCode: parser.getString(STOCK_CODE_LEN),
+				Period: parser.getUint16(),
+				Unknown1: parser.getUint16(),
+				Offset: parser.getUint16(),
+				Count: parser.getUint16(),
+				Unknown2: parser.getUint32(),
+				Unknown3: parser.getUint32(),
+				Unknown4: parser.getUint16(),
+			}
+		},
+		DecodeResp: func(req Request, data []byte) (error, interface{}) {
+			return NewPeriodDataParser(req, data).ParseIndex()
+		},
+	})
+	RegisterCommand(&Command{
+		Cmd: CMD_MINUTE_TIME,
+		Name: "minute_time",
+		DecodeReq: func(parser *ReqParser, header Header) Request {
+			return &MinuteTimeReq{
+				Header: header,
+				Location: parser.getUint16(),
+				StockCode: parser.getString(STOCK_CODE_LEN),
+				Reserved: parser.getUint32(),
+			}
+		},
+		DecodeResp: func(req Request, data []byte) (error, interface{}) {
+			return NewMinuteTimeParser(req, data).Parse()
+		},
+	})
+	RegisterCommand(&Command{
+		Cmd: CMD_HIS_MINUTE_TIME,
+		Name: "his_minute_time",
+		DecodeReq: func(parser *ReqParser, header Header) Request {
+			return &HisMinuteTimeReq{
+				Header: header,
+				Date: parser.getUint32(),
+				Location: parser.getByte(),
+				StockCode: parser.getString(STOCK_CODE_LEN),
+			}
+		},
+		DecodeResp: func(req Request, data []byte) (error, interface{}) {
+			return NewHisMinuteTimeParser(req, data).Parse()
+		},
+	})
+	RegisterCommand(&Command{
+		Cmd: CMD_PERIOD_HIS_DATA,
+		Name: "period_his_data",
+		DecodeReq: func(parser *ReqParser, header Header) Request {
+			return &PeriodHisDataReq{
+				Header: header,
+				Location: parser.getUint16(),
+				StockCode: parser.getString(STOCK_CODE_LEN),
+				StartDate: parser.getUint32(),
+				EndDate: parser.getUint32(),
+				Period: parser.getUint16(),
+			}
+		},
+		DecodeResp: func(req Request, data []byte) (error, interface{}) {
+			return NewPeriodHisDataParser(req, data).Parse()
+		},
+	})
+	RegisterCommand(&Command{
+		Cmd: CMD_NAMES_LEN,
+		Name: "names_len",
+		DecodeReq: func(parser *ReqParser, header Header) Request {
+			return &NamesLenReq{Header: header, Block: parser.getUint16(), Date: parser.getUint32()}
+		},
+		DecodeResp: func(req Request, data []byte) (error, interface{}) {
+			return NewNamesLenParser(req, data).Parse()
+		},
+	})
+	RegisterCommand(&Command{
+		Cmd: CMD_NAMES,
+		Name: "names",
+		DecodeReq: func(parser *ReqParser, header Header) Request {
+			return &NamesReq{Header: header, Block: parser.getUint16(), Offset: parser.getUint16()}
+		},
+		DecodeResp: func(req Request, data []byte) (error, interface{}) {
+			err, count, names := NewNamesParser(req, data).Parse()
+			if err != nil {
+				return err, nil
+			}
+			return nil, &NamesData{Count: count, Data: names}
+		},
+	})
+	RegisterCommand(&Command{
+		Cmd: CMD_GET_FILE_LEN,
+		Name: "get_file_len",
+		DecodeReq: func(parser *ReqParser, header Header) Request {
+			return &GetFileLenReq{Header: header, FileName: parser.getFileName(40)}
+		},
+		DecodeResp: func(req Request, data []byte) (error, interface{}) {
+			return NewGetFileLenParser(req, data).Parse()
+		},
+	})
+	RegisterCommand(&Command{
+		Cmd: CMD_GET_FILE_DATA,
+		Name: "get_file_data",
+		DecodeReq: func(parser *ReqParser, header Header) Request {
+			return &GetFileDataReq{
+				Header: header,
+				Offset: parser.getUint32(),
+				Length: parser.getUint32(),
+				FileName: parser.getFileName(100),
+			}
+		},
+		DecodeResp: func(req Request, data []byte) (error, interface{}) {
+			err, length, fileData := NewGetFileDataParser(req, data).Parse()
+			if err != nil {
+				return err, nil
+			}
+			return nil, &FileData{Length: length, Data: fileData}
+		},
+	})
+}
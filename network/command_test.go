@@ -0,0 +1,146 @@
+package network
+
+import (
+	"testing"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"github.com/stephenlyu/tds/entity"
+	"github.com/stephenlyu/tds/datasource/tdx"
+)
+
+func TestCommands(t *testing.T) {
+	names := map[string]bool{}
+	commands := Commands()
+	for i, command := range commands {
+		if i > 0 && commands[i - 1].Cmd >= command.Cmd {
+			t.Errorf("commands not sorted: 0x%04x 0x%04x", commands[i - 1].Cmd, command.Cmd)
+		}
+		if command.Name == "" || names[command.Name] {
+			t.Errorf("bad command name %q of 0x%04x", command.Name, command.Cmd)
+		}
+		names[command.Name] = true
+		if command.DecodeReq == nil || command.DecodeResp == nil {
+			t.Errorf("command %s not complete", command.Name)
+		}
+		if GetCommand(command.Cmd) != command || GetCommandName(command.Cmd) != command.Name {
+			t.Errorf("command %s not found", command.Name)
+		}
+	}
+
+	if GetCommand(0xffff) != nil || GetCommandName(0xffff) != "0xffff" {
+		t.Error("unexpected command 0xffff")
+	}
+}
+
+func TestCommandDecodeResp(t *testing.T) {
+	err, result := GetCommand(CMD_GET_FILE_DATA).DecodeResp(NewGetFileDataReq(1, "zhb.zip", 0, 3), EncodeResp(1, CMD_GET_FILE_DATA, EncodeFileData([]byte{1, 2, 3}), false))
+	if err != nil || !reflect.DeepEqual(result, &FileData{Length: 3, Data: []byte{1, 2, 3}}) {
+		t.Errorf("unexpected result %+v, error: %v", result, err)
+	}
+
+	err, result = GetCommand(CMD_NAMES).DecodeResp(NewNamesReq(2, BLOCK_SH_A, 0), EncodeResp(2, CMD_NAMES, EncodeNamesData(1, []byte{4, 5}), true))
+	if err != nil || !reflect.DeepEqual(result, &NamesData{Count: 1, Data: []byte{4, 5}}) {
+		t.Errorf("unexpected result %+v, error: %v", result, err)
+	}
+
+	err, _ = GetCommand(CMD_HEART_BEAT).DecodeResp(NewHeartBeatReq(3), EncodeResp(4, CMD_HEART_BEAT, nil, false))
+	if !errors.Is(err, ErrBadSeqId) {
+		t.Errorf("expected ErrBadSeqId, got %v", err)
+	}
+}
+
+// 除权除息返回所有类别, K线返回指数记录, API再按需转换
+func TestCommandDecodeRichResp(t *testing.T) {
+	events := map[string][]*InfoExEvent{
+		"600000.SH": {
+			{Date: 20180712, Category: XDXR_DIVIDEND, Bonus: 1.5},
+			{Date: 20180713, Category: XDXR_SPLIT, SplitRatio: 2},
+		},
+	}
+	req := NewInfoExReq(5)
+	req.AddCode(entity.ParseSecurityUnsafe("600000.SH"))
+	err, result := GetCommand(CMD_INFO_EX).DecodeResp(req, EncodeResp(5, CMD_INFO_EX, EncodeInfoExEvents(events), false))
+	if err != nil || !reflect.DeepEqual(result, events) {
+		t.Errorf("unexpected result %+v, error: %v", result, err)
+	}
+	items := DividendItems(events)
+	if len(items["600000.SH"]) != 1 || items["600000.SH"][0].Bonus != 1.5 {
+		t.Errorf("unexpected dividend items %+v", items)
+	}
+
+	records := []IndexRecord{
+		{Record: entity.Record{Date: tdxdatasource.DayDateToTimestamp(20181102), Open: 10, Close: 10.5, High: 11, Low: 9.5, Volume: 1000, Amount: 10500}, UpCount: 900, DownCount: 300},
+	}
+	data := EncodeResp(6, CMD_PERIOD_DATA, EncodeIndexPeriodData(PERIOD_DAY, records), false)
+
+	// 600000.SH不是指数, 需要Index强制按指数解析
+	periodReq := NewPeriodDataReq(6, entity.ParseSecurityUnsafe("600000.SH"), PERIOD_DAY, 0, 1)
+	periodReq.Index = true
+	err, result = GetCommand(CMD_PERIOD_DATA).DecodeResp(periodReq, data)
+	if err != nil || !reflect.DeepEqual(result, records) {
+		t.Errorf("unexpected result %+v, error: %v", result, err)
+	}
+	if plain := PlainRecords(records); !reflect.DeepEqual(plain, []entity.Record{records[0].Record}) {
+		t.Errorf("unexpected plain records %+v", plain)
+	}
+}
+
+func TestDoUnknownCmd(t *testing.T) {
+	api := &API{}
+	err, _ := api.Do(context.Background(), &RawReq{Header: Header{Zip: 0xc, PacketType: 1, Len: 2, Len1: 2, Cmd: 0xffff}})
+	if !errors.Is(err, ErrUnknownCmd) || IsRetryable(err) {
+		t.Errorf("expected fatal ErrUnknownCmd, got %v", err)
+	}
+}
+
+// 只有盘口的响应写入raw.dat
+func TestLogResp(t *testing.T) {
+	security := entity.ParseSecurityUnsafe("000001.SZ")
+	bidReq := NewBidReq(1)
+	bidReq.AddCode(security)
+	transReq := NewHisTransReq(2, 20181102, security, 0, 10)
+
+	captures := []*Capture{}
+	bodies := map[uint16][]byte{
+		CMD_BID: EncodeBidData(map[string]*Bid{"000001.SZ": {StockCode: "000001.SZ", Close: 1050}}),
+		CMD_HIS_TRANS: EncodeHisTransData([]Transaction{{Minute: 570, Price: 1050, Volume: 10}}),
+	}
+	for _, req := range []WritableRequest{bidReq, transReq} {
+		buf := new(bytes.Buffer)
+		req.Write(buf)
+		resp := EncodeResp(req.GetSeqId(), req.GetCmd(), bodies[req.GetCmd()], false)
+		captures = append(captures, &Capture{Cmd: req.GetCmd(), SeqId: req.GetSeqId(), Req: hex.EncodeToString(buf.Bytes()), Resp: hex.EncodeToString(resp)})
+	}
+
+	wd, _ := os.Getwd()
+	chk(os.Chdir(t.TempDir()))
+	defer os.Chdir(wd)
+
+	api := createReplayAPI(t, captures)
+	api.SetLogEnabled(true)
+
+	if err, _ := api.GetHistoryTransaction(security, 20181102, 0, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err, _ := api.GetBid([]*entity.Security{security}); err != nil {
+		t.Fatal(err)
+	}
+	api.Cleanup()
+
+	data, err := os.ReadFile("raw.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logged, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatalf("expect one response, got %s", data)
+	}
+	if _, cmd := NewRespParser(logged).GetCmd(); cmd != CMD_BID {
+		t.Fatalf("bad log: %s", data)
+	}
+}
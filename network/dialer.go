@@ -0,0 +1,214 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Dialer creates the underlying connection of API, *net.Dialer satisfies it.
+// Dialing should stop once ctx is done, API applies DialTimeout through ctx.
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+type DialerFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+func (f DialerFunc) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return f(ctx, network, address)
+}
+
+// bindDeadline makes the blocking calls on conn fail once ctx is done, the returned function undoes it.
+func bindDeadline(ctx context.Context, conn net.Conn) func() {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if ctx.Done() == nil {
+		return func() {
+			conn.SetDeadline(time.Time{})
+		}
+	}
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+		conn.SetDeadline(time.Time{})
+	}
+}
+
+type Socks5Auth struct {
+	User string
+	Password string
+}
+
+type socks5Dialer struct {
+	proxyAddr string
+	auth *Socks5Auth
+	forward Dialer
+}
+
+// NewSocks5Dialer returns a dialer connecting through the SOCKS5 proxy at proxyAddr.
+// auth can be nil if the proxy needs no authentication, forward is used to reach the proxy, nil means net.Dialer.
+// Both connecting to the proxy and the negotiation stop once the ctx of DialContext is done.
+func NewSocks5Dialer(proxyAddr string, auth *Socks5Auth, forward Dialer) Dialer {
+	if forward == nil {
+		forward = &net.Dialer{}
+	}
+	return &socks5Dialer{
+		proxyAddr: proxyAddr,
+		auth: auth,
+		forward: forward,
+	}
+}
+
+func (this *socks5Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	if network != "tcp" && network != "tcp4" && network != "tcp6" {
+		return nil, errors.New("socks5: network not supported: " + network)
+	}
+
+	conn, err := this.forward.DialContext(ctx, "tcp", this.proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	unbind := bindDeadline(ctx, conn)
+	err = this.connect(conn, address)
+	unbind()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (this *socks5Dialer) connect(conn net.Conn, address string) error {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 0xffff {
+		return errors.New("socks5: bad port " + portStr)
+	}
+
+	// Greeting
+	methods := []byte{0x00}
+	if this.auth != nil {
+		methods = append(methods, 0x02)
+	}
+	buf := append([]byte{0x05, byte(len(methods))}, methods...)
+	if _, err = conn.Write(buf); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err = io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != 0x05 {
+		return fmt.Errorf("socks5: bad version %d", reply[0])
+	}
+
+	switch reply[1] {
+	case 0x00:
+	case 0x02:
+		if this.auth == nil {
+			return errors.New("socks5: proxy requires authentication")
+		}
+		if err = this.authenticate(conn); err != nil {
+			return err
+		}
+	default:
+		return errors.New("socks5: no acceptable authentication method")
+	}
+
+	// Connect
+	buf = []byte{0x05, 0x01, 0x00}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			buf = append(buf, 0x01)
+			buf = append(buf, ip4...)
+		} else {
+			buf = append(buf, 0x04)
+			buf = append(buf, ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return errors.New("socks5: host name too long")
+		}
+		buf = append(buf, 0x03, byte(len(host)))
+		buf = append(buf, host...)
+	}
+	buf = append(buf, byte(port >> 8), byte(port))
+	if _, err = conn.Write(buf); err != nil {
+		return err
+	}
+
+	header := make([]byte, 4)
+	if _, err = io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[0] != 0x05 {
+		return fmt.Errorf("socks5: bad version %d", header[0])
+	}
+	if header[1] != 0x00 {
+		return fmt.Errorf("socks5: connect fail, reply code %d", header[1])
+	}
+
+	var addrLen int
+	switch header[3] {
+	case 0x01:
+		addrLen = net.IPv4len
+	case 0x04:
+		addrLen = net.IPv6len
+	case 0x03:
+		l := make([]byte, 1)
+		if _, err = io.ReadFull(conn, l); err != nil {
+			return err
+		}
+		addrLen = int(l[0])
+	default:
+		return fmt.Errorf("socks5: bad address type %d", header[3])
+	}
+
+	// Bound address and port are not used
+	_, err = io.ReadFull(conn, make([]byte, addrLen + 2))
+	return err
+}
+
+func (this *socks5Dialer) authenticate(conn net.Conn) error {
+	if len(this.auth.User) > 255 || len(this.auth.Password) > 255 {
+		return errors.New("socks5: user or password too long")
+	}
+
+	buf := []byte{0x01, byte(len(this.auth.User))}
+	buf = append(buf, this.auth.User...)
+	buf = append(buf, byte(len(this.auth.Password)))
+	buf = append(buf, this.auth.Password...)
+	if _, err := conn.Write(buf); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[1] != 0x00 {
+		return errors.New("socks5: authentication fail")
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveSocks5 accepts one client, checks user/password and relays to the requested address.
+func serveSocks5(t *testing.T, l net.Listener, user, password string) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 2)
+	io.ReadFull(conn, buf)
+	io.ReadFull(conn, make([]byte, buf[1]))
+	conn.Write([]byte{0x05, 0x02})
+
+	io.ReadFull(conn, buf)
+	u := make([]byte, buf[1])
+	io.ReadFull(conn, u)
+	io.ReadFull(conn, buf[:1])
+	p := make([]byte, buf[0])
+	io.ReadFull(conn, p)
+	if string(u) != user || string(p) != password {
+		conn.Write([]byte{0x01, 0x01})
+		return
+	}
+	conn.Write([]byte{0x01, 0x00})
+
+	header := make([]byte, 4)
+	io.ReadFull(conn, header)
+	if header[3] != 0x01 {
+		t.Errorf("unexpected address type %d", header[3])
+		return
+	}
+	addr := make([]byte, 6)
+	io.ReadFull(conn, addr)
+	target := &net.TCPAddr{IP: net.IP(addr[:4]), Port: int(addr[4]) << 8 | int(addr[5])}
+
+	remote, err := net.DialTCP("tcp", nil, target)
+	if err != nil {
+		conn.Write([]byte{0x05, 0x05, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer remote.Close()
+	conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 127, 0, 0, 1, 0, 0})
+
+	go io.Copy(remote, conn)
+	io.Copy(conn, remote)
+}
+
+func TestSocks5Dialer(t *testing.T) {
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	go func() {
+		conn, err := echo.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(conn, conn)
+	}()
+
+	proxy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+	go serveSocks5(t, proxy, "user", "secret")
+
+	dialer := NewSocks5Dialer(proxy.Addr().String(), &Socks5Auth{User: "user", Password: "secret"}, nil)
+	conn, err := dialer.DialContext(context.Background(), "tcp", echo.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	msg := []byte("hello")
+	conn.Write(msg)
+	reply := make([]byte, len(msg))
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(reply, msg) {
+		t.Fatalf("got %q, want %q", reply, msg)
+	}
+}
+
+func TestSocks5DialerBadAuth(t *testing.T) {
+	proxy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+	go serveSocks5(t, proxy, "user", "secret")
+
+	dialer := NewSocks5Dialer(proxy.Addr().String(), &Socks5Auth{User: "user", Password: "wrong"}, nil)
+	if _, err := dialer.DialContext(context.Background(), "tcp", "127.0.0.1:7709"); err == nil {
+		t.Fatal("expect authentication error")
+	}
+}
+
+// CONNECT的响应版本号不是5
+func TestSocks5DialerBadConnectVersion(t *testing.T) {
+	proxy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+	go func() {
+		conn, err := proxy.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		buf := make([]byte, 2)
+		io.ReadFull(conn, buf)
+		io.ReadFull(conn, make([]byte, buf[1]))
+		conn.Write([]byte{0x05, 0x00})
+
+		io.ReadFull(conn, make([]byte, 10))
+		conn.Write([]byte{0x04, 0x00, 0x00, 0x01, 127, 0, 0, 1, 0, 0})
+	}()
+
+	dialer := NewSocks5Dialer(proxy.Addr().String(), nil, nil)
+	if _, err := dialer.DialContext(context.Background(), "tcp", "127.0.0.1:7709"); err == nil || !strings.Contains(err.Error(), "bad version") {
+		t.Fatalf("expect bad version, got %v", err)
+	}
+}
+
+// silentListener accepts connections but never replies.
+func silentListener(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	return l
+}
+
+func TestSocks5DialerDeadline(t *testing.T) {
+	proxy := silentListener(t)
+	defer proxy.Close()
+	dialer := NewSocks5Dialer(proxy.Addr().String(), nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100 * time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := dialer.DialContext(ctx, "tcp", "127.0.0.1:7709"); err == nil {
+		t.Fatal("expect timeout")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("negotiation not limited by deadline, took %v", elapsed)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(100 * time.Millisecond, cancel)
+	start = time.Now()
+	if _, err := dialer.DialContext(ctx, "tcp", "127.0.0.1:7709"); err == nil {
+		t.Fatal("expect cancel")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("negotiation not cancelled, took %v", elapsed)
+	}
+}
+
+func TestDialTimeoutWithDialer(t *testing.T) {
+	proxy := silentListener(t)
+	defer proxy.Close()
+
+	options := DefaultOptions()
+	options.Hosts = []string{"127.0.0.1:7709"}
+	options.InitialCap = 1
+	options.DialTimeout = 100 * time.Millisecond
+	options.Dialer = NewSocks5Dialer(proxy.Addr().String(), nil, nil)
+
+	start := time.Now()
+	if err, _ := CreateAPIWithOptions(options); err == nil {
+		t.Fatal("expect timeout")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("DialTimeout not applied to Dialer, took %v", elapsed)
+	}
+}
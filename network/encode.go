@@ -0,0 +1,402 @@
+package network
+
+import (
+	"bytes"
+	"encoding/binary"
+	"compress/zlib"
+	"math"
+	"reflect"
+	"sort"
+	"github.com/stephenlyu/tds/entity"
+	"github.com/stephenlyu/tds/datasource/tdx"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// 响应数据的编码，和各Parser互逆，用于模拟服务器和测试
+
+func writeFloat32(writer *bytes.Buffer, v float32) {
+	writeUInt32(writer, math.Float32bits(v))
+}
+
+func writeStock(writer *bytes.Buffer, security string) {
+	s := entity.ParseSecurityUnsafe(security)
+	writer.WriteByte(MarketLocationFromSecurity(s))
+	writer.WriteString(s.GetCode())
+}
+
+// writeVarint 首字节6位有效，0x40为符号位，0x80为后续标志；其余字节7位有效
+func writeVarint(writer *bytes.Buffer, v uint64, negative bool) {
+	b := byte(v & 0x3f)
+	if negative {
+		b |= 0x40
+	}
+	v >>= 6
+	for {
+		if v == 0 {
+			writer.WriteByte(b)
+			return
+		}
+		writer.WriteByte(b | 0x80)
+		b = byte(v & 0x7f)
+		v >>= 7
+	}
+}
+
+// writeData is the inverse of RespParser.parseData
+func writeData(writer *bytes.Buffer, v int) {
+	if v < 0 {
+		writeVarint(writer, uint64(-v), true)
+	} else {
+		writeVarint(writer, uint64(v), false)
+	}
+}
+
+// writeData2 is the inverse of RespParser.parseData2
+func writeData2(writer *bytes.Buffer, v int) {
+	writeVarint(writer, uint64(uint32(v)), false)
+}
+
+func toPrice(v float64) int {
+	return int(math.Floor(v * 1000 + 0.5))
+}
+
+// toTdxDate is the inverse of tdxdatasource.DateToTimestamp
+func toTdxDate(period uint16, ts uint64) uint32 {
+	day := tdxdatasource.TimestampToDayDate(ts)
+	if !isIntradayPeriod(period) {
+		return day
+	}
+
+	minute := uint32((ts - tdxdatasource.DayDateToTimestamp(day)) / 60000)
+	year, month, d := day / 10000, day / 100 % 100, day % 100
+	return minute << 16 | ((year - 2004) * 2048 + month * 100 + d)
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := reflect.ValueOf(m).MapKeys()
+	result := make([]string, len(keys))
+	for i, key := range keys {
+		result[i] = key.String()
+	}
+	sort.Strings(result)
+	return result
+}
+
+// XorData is the obfuscation of bid data, applying it twice gives the original data.
+func XorData(data []byte) []byte {
+	result := make([]byte, len(data))
+	for i, b := range data {
+		result[i] = b ^ 57
+	}
+	return result
+}
+
+func ZipData(data []byte) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+// EncodeResp builds the response frame, data is zlib compressed if compress is true and it makes a difference in length.
+func EncodeResp(seqId uint32, cmd uint16, data []byte, compress bool) []byte {
+	len1 := len(data)
+	if compress && len(data) > 0 {
+		if zipped := ZipData(data); len(zipped) != len(data) {
+			data = zipped
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	buf.Write(RESP_MAGIC)
+	buf.WriteByte(0x0c)
+	writeUInt32(buf, seqId)
+	buf.WriteByte(0)
+	writeUInt16(buf, cmd)
+	writeUInt16(buf, uint16(len(data)))
+	writeUInt16(buf, uint16(len1))
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func EncodeBidData(bids map[string]*Bid) []byte {
+	buf := new(bytes.Buffer)
+	writeUInt16(buf, uint16(len(bids)))
+
+	for _, security := range sortedKeys(bids) {
+		bid := bids[security]
+		writeStock(buf, security)
+		writeUInt16(buf, 0)	// 未知
+
+		base := int(bid.Close)
+		writeData2(buf, base)
+		writeData(buf, int(bid.YesterdayClose) - base)
+		writeData(buf, int(bid.Open) - base)
+		writeData(buf, int(bid.High) - base)
+		writeData(buf, int(bid.Low) - base)
+
+		buf.Write(make([]byte, 5))
+
+		writeData2(buf, int(bid.Vol))
+		writeData2(buf, 0)
+		writeFloat32(buf, bid.Amount)
+		writeData2(buf, int(bid.InnerVol))
+		writeData2(buf, int(bid.OuterVol))
+
+		writeData2(buf, 0)
+		writeData2(buf, 0)
+
+		levels := [][4]uint32{
+			{bid.BuyPrice1, bid.SellPrice1, bid.BuyVol1, bid.SellVol1},
+			{bid.BuyPrice2, bid.SellPrice2, bid.BuyVol2, bid.SellVol2},
+			{bid.BuyPrice3, bid.SellPrice3, bid.BuyVol3, bid.SellVol3},
+			{bid.BuyPrice4, bid.SellPrice4, bid.BuyVol4, bid.SellVol4},
+			{bid.BuyPrice5, bid.SellPrice5, bid.BuyVol5, bid.SellVol5},
+		}
+		for _, level := range levels {
+			writeData(buf, int(level[0]) - base)
+			writeData(buf, int(level[1]) - base)
+			writeData2(buf, int(level[2]))
+			writeData2(buf, int(level[3]))
+		}
+	}
+
+	return XorData(buf.Bytes())
+}
+
+// EncodePeriodData encodes records of any PERIOD_XXX, prices are rounded to 0.001.
+func EncodePeriodData(period uint16, records []entity.Record) []byte {
+	buf := new(bytes.Buffer)
+	writeUInt16(buf, uint16(len(records)))
+
+	var priceBase int
+	for i := range records {
+		priceBase = writePeriodRecord(buf, period, &records[i], i == 0, priceBase)
+	}
+	return buf.Bytes()
+}
+
+// EncodeIndexPeriodData is the same as EncodePeriodData except the up and down counts following every record.
+func EncodeIndexPeriodData(period uint16, records []IndexRecord) []byte {
+	buf := new(bytes.Buffer)
+	writeUInt16(buf, uint16(len(records)))
+
+	var priceBase int
+	for i := range records {
+		priceBase = writePeriodRecord(buf, period, &records[i].Record, i == 0, priceBase)
+		writeUInt16(buf, records[i].UpCount)
+		writeUInt16(buf, records[i].DownCount)
+	}
+	return buf.Bytes()
+}
+
+// writePeriodRecord returns the close price, which is the base of the next open price
+func writePeriodRecord(buf *bytes.Buffer, period uint16, record *entity.Record, first bool, priceBase int) int {
+	writeUInt32(buf, toTdxDate(period, record.Date))
+
+	open := toPrice(record.Open)
+	if first {
+		writeData2(buf, open)
+	} else {
+		writeData(buf, open - priceBase)
+	}
+	writeData(buf, toPrice(record.Close) - open)
+	writeData(buf, toPrice(record.High) - open)
+	writeData(buf, toPrice(record.Low) - open)
+	writeFloat32(buf, float32(record.Volume))
+	writeFloat32(buf, float32(record.Amount))
+
+	return toPrice(record.Close)
+}
+
+func EncodeInfoExData(infoEx map[string][]*InfoExItem) []byte {
+	events := map[string][]*InfoExEvent{}
+	for security, items := range infoEx {
+		events[security] = []*InfoExEvent{}
+		for _, item := range items {
+			events[security] = append(events[security], NewDividendEvent(item))
+		}
+	}
+	return EncodeInfoExEvents(events)
+}
+
+func EncodeInfoExEvents(events map[string][]*InfoExEvent) []byte {
+	buf := new(bytes.Buffer)
+	writeUInt16(buf, uint16(len(events)))
+
+	for _, security := range sortedKeys(events) {
+		stockEvents := events[security]
+		writeStock(buf, security)
+		writeUInt16(buf, uint16(len(stockEvents)))
+
+		for _, event := range stockEvents {
+			writeStock(buf, security)
+			buf.WriteByte(0)
+			writeUInt32(buf, event.Date)
+			buf.WriteByte(event.Category)
+			switch event.Category {
+			case XDXR_DIVIDEND:
+				writeFloat32(buf, event.Bonus * 10)
+				writeFloat32(buf, event.RationedSharePrice)
+				writeFloat32(buf, event.DeliveredShares * 10)
+				writeFloat32(buf, event.RationedShares * 10)
+			case XDXR_SPLIT, XDXR_NON_TRADABLE_SPLIT:
+				buf.Write(make([]byte, 8))
+				writeFloat32(buf, event.SplitRatio)
+				buf.Write(make([]byte, 4))
+			case XDXR_CALL_WARRANT, XDXR_PUT_WARRANT:
+				writeFloat32(buf, event.StrikePrice)
+				buf.Write(make([]byte, 4))
+				writeFloat32(buf, event.WarrantShares)
+				buf.Write(make([]byte, 4))
+			default:
+				writeFloat32(buf, event.FloatSharesBefore)
+				writeFloat32(buf, event.TotalSharesBefore)
+				writeFloat32(buf, event.FloatSharesAfter)
+				writeFloat32(buf, event.TotalSharesAfter)
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+func EncodeFinanceData(finances map[string]*Finance) []byte {
+	buf := new(bytes.Buffer)
+	writeUInt16(buf, uint16(len(finances)))
+
+	for _, security := range sortedKeys(finances) {
+		writeStock(buf, security)
+		buf.Write(make([]byte, 41 - (3 + STOCK_CODE_LEN)))
+
+		// 字段顺序和数据中的顺序一致
+		value := reflect.ValueOf(finances[security]).Elem()
+		for i := 0; i < value.NumField(); i++ {
+			writeFloat32(buf, float32(value.Field(i).Float()))
+		}
+
+		buf.Write(make([]byte, 4))
+	}
+	return buf.Bytes()
+}
+
+func EncodeInstantTransData(transactions []Transaction) []byte {
+	buf := new(bytes.Buffer)
+	writeUInt16(buf, uint16(len(transactions)))
+
+	var priceBase int
+	for i, trans := range transactions {
+		writeUInt16(buf, trans.Minute)
+		if i == 0 {
+			writeData2(buf, int(trans.Price))
+		} else {
+			writeData(buf, int(trans.Price) - priceBase)
+		}
+		priceBase = int(trans.Price)
+		writeData2(buf, int(trans.Volume))
+		writeData2(buf, int(trans.Count))
+		buf.WriteByte(trans.BS)
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+// EncodeHisTransData encodes transactions of one day, Date is not in the data.
+func EncodeHisTransData(transactions []Transaction) []byte {
+	buf := new(bytes.Buffer)
+	writeUInt16(buf, uint16(len(transactions)))
+	writeUInt32(buf, 0)
+
+	var priceBase int
+	for i, trans := range transactions {
+		writeUInt16(buf, trans.Minute)
+		if i == 0 {
+			writeData2(buf, int(trans.Price))
+		} else {
+			writeData(buf, int(trans.Price) - priceBase)
+		}
+		priceBase = int(trans.Price)
+		writeData2(buf, int(trans.Volume))
+		buf.WriteByte(trans.BS)
+		writeData2(buf, int(trans.Count))
+	}
+	return buf.Bytes()
+}
+
+func EncodePeriodHisData(data []byte) []byte {
+	buf := new(bytes.Buffer)
+	buf.Write(make([]byte, 6))
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func EncodeFileLenData(length uint32) []byte {
+	buf := new(bytes.Buffer)
+	writeUInt32(buf, length)
+	return buf.Bytes()
+}
+
+func EncodeFileData(data []byte) []byte {
+	buf := new(bytes.Buffer)
+	writeUInt32(buf, uint32(len(data)))
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func EncodeNamesLenData(count uint16) []byte {
+	buf := new(bytes.Buffer)
+	writeUInt16(buf, count)
+	return buf.Bytes()
+}
+
+// EncodeNamesData encodes count name records, each record is 29 bytes.
+func EncodeNamesData(count uint16, data []byte) []byte {
+	buf := new(bytes.Buffer)
+	writeUInt16(buf, count)
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+// EncodeSecurityList encodes the records consumed by ParseSecurityList, the exchange suffix of codes is dropped,
+// Pinyin and Block are derived from name and code.
+func EncodeSecurityList(infos []*SecurityInfo) []byte {
+	encoder := simplifiedchinese.GBK.NewEncoder()
+	buf := new(bytes.Buffer)
+	for _, info := range infos {
+		record := make([]byte, SECURITY_RECORD_LEN)
+		copy(record, info.Code[:STOCK_CODE_LEN])
+		binary.LittleEndian.PutUint16(record[6:], info.VolUnit)
+		name, _ := encoder.Bytes([]byte(info.Name))
+		copy(record[8:8 + SECURITY_NAME_LEN], name)
+		binary.LittleEndian.PutUint32(record[16:], info.Reserved1)
+		record[20] = info.DecimalPoint
+		binary.LittleEndian.PutUint32(record[21:], math.Float32bits(float32(info.PreClose)))
+		binary.LittleEndian.PutUint32(record[25:], info.Reserved2)
+		buf.Write(record)
+	}
+	return buf.Bytes()
+}
+
+func encodeMinuteTimePoints(points []MinuteTimePoint, skip int) []byte {
+	buf := new(bytes.Buffer)
+	writeUInt16(buf, uint16(len(points)))
+	buf.Write(make([]byte, skip))
+
+	price := 0
+	for _, point := range points {
+		writeData(buf, int(point.Price) - price)
+		price = int(point.Price)
+		writeData(buf, point.Reserved)
+		writeData(buf, int(point.Volume))
+	}
+	return buf.Bytes()
+}
+
+// EncodeMinuteTimeData encodes the response of CMD_MINUTE_TIME, Minute of points is not encoded.
+func EncodeMinuteTimeData(points []MinuteTimePoint) []byte {
+	return encodeMinuteTimePoints(points, 2)
+}
+
+func EncodeHisMinuteTimeData(points []MinuteTimePoint) []byte {
+	return encodeMinuteTimePoints(points, 4)
+}
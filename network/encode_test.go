@@ -0,0 +1,413 @@
+package network
+
+import (
+	"testing"
+	"testing/quick"
+	"math/rand"
+	"reflect"
+	"bytes"
+	"fmt"
+	"github.com/stephenlyu/tds/entity"
+	"github.com/stephenlyu/tds/datasource/tdx"
+)
+
+var quickConfig = &quick.Config{MaxCount: 500}
+
+func randomSecurity(r *rand.Rand) string {
+	if r.Intn(2) == 0 {
+		return fmt.Sprintf("%06d.SZ", r.Intn(400000))
+	}
+	return fmt.Sprintf("6%05d.SH", r.Intn(100000))
+}
+
+// randomFloat32 returns values which are exact in float32
+func randomFloat32(r *rand.Rand) float32 {
+	return float32(r.Int31n(1 << 24) - 1 << 23) / 64
+}
+
+func TestDataRoundTrip(t *testing.T) {
+	f := func(v int32, signed bool) bool {
+		buf := new(bytes.Buffer)
+		if signed {
+			writeData(buf, int(v))
+		} else {
+			writeData2(buf, int(uint32(v)))
+		}
+
+		parser := &RespParser{Data: buf.Bytes()}
+		var ret int
+		if signed {
+			ret = parser.parseData()
+		} else {
+			ret = parser.parseData2()
+		}
+		return parser.Err() == nil && parser.Current == buf.Len() && (signed && ret == int(v) || !signed && uint32(ret) == uint32(v))
+	}
+	if err := quick.Check(f, quickConfig); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestXorData(t *testing.T) {
+	f := func(data []byte) bool {
+		return bytes.Equal(XorData(XorData(data)), data)
+	}
+	if err := quick.Check(f, quickConfig); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBidRoundTrip(t *testing.T) {
+	f := func(seed int64, compress bool) bool {
+		r := rand.New(rand.NewSource(seed))
+		bids := map[string]*Bid{}
+		securities := []*entity.Security{}
+		for i := r.Intn(20); i > 0; i-- {
+			bid := &Bid{}
+			value := reflect.ValueOf(bid).Elem()
+			for j := 1; j < value.NumField(); j++ {
+				if value.Field(j).Kind() == reflect.Uint32 {
+					value.Field(j).SetUint(uint64(r.Uint32()))
+				}
+			}
+			bid.Amount = randomFloat32(r)
+			bid.StockCode = randomSecurity(r)
+			bids[bid.StockCode] = bid
+			securities = append(securities, entity.ParseSecurityUnsafe(bid.StockCode))
+		}
+
+		req := NewBidReq(1)
+		for _, security := range securities {
+			req.AddCode(security)
+		}
+		err, result := NewBidParser(req, EncodeResp(1, CMD_BID, EncodeBidData(bids), compress)).Parse()
+		return err == nil && reflect.DeepEqual(result, bids)
+	}
+	if err := quick.Check(f, quickConfig); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTransRoundTrip(t *testing.T) {
+	f := func(seed int64, compress bool) bool {
+		r := rand.New(rand.NewSource(seed))
+		transactions := make([]Transaction, r.Intn(100))
+		for i := range transactions {
+			transactions[i] = Transaction{
+				Date: 20181102,
+				Minute: uint16(r.Intn(1440)),
+				Price: uint32(r.Int31()),
+				Volume: r.Uint32(),
+				Count: r.Uint32(),
+				BS: byte(r.Intn(2)),
+			}
+		}
+
+		security := entity.ParseSecurityUnsafe(randomSecurity(r))
+		hisReq := NewHisTransReq(2, 20181102, security, 0, uint16(len(transactions)))
+		err, hisResult := NewHisTransParser(hisReq, EncodeResp(2, CMD_HIS_TRANS, EncodeHisTransData(transactions), compress)).Parse()
+		if err != nil || !reflect.DeepEqual(hisResult, transactions) {
+			return false
+		}
+
+		for i := range transactions {
+			transactions[i].Date = 0
+		}
+		req := NewInstantTransReq(3, security, 0, uint16(len(transactions)))
+		err, result := NewInstantTransParser(req, EncodeResp(3, CMD_INSTANT_TRANS, EncodeInstantTransData(transactions), compress)).Parse()
+		return err == nil && reflect.DeepEqual(result, transactions)
+	}
+	if err := quick.Check(f, quickConfig); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPeriodDataRoundTrip(t *testing.T) {
+	f := func(seed int64, compress bool) bool {
+		r := rand.New(rand.NewSource(seed))
+		periods := []uint16{PERIOD_MINUTE, PERIOD_MINUTE5, PERIOD_MINUTE15, PERIOD_MINUTE30, PERIOD_HOUR, PERIOD_DAY, PERIOD_WEEK, PERIOD_MONTH, PERIOD_QUARTER, PERIOD_YEAR}
+		period := periods[r.Intn(len(periods))]
+
+		records := make([]entity.Record, r.Intn(100))
+		ts := tdxdatasource.DayDateToTimestamp(uint32(20050101 + r.Intn(13) * 10000 + r.Intn(12) * 100 + r.Intn(28)))
+		for i := range records {
+			price := r.Intn(1000000)
+			records[i] = entity.Record{
+				Date: ts + uint64(r.Intn(1440)) * 60000,
+				Open: float64(price) / 1000,
+				Close: float64(price + r.Intn(2000) - 1000) / 1000,
+				High: float64(price + r.Intn(1000)) / 1000,
+				Low: float64(price - r.Intn(1000)) / 1000,
+				Volume: float64(randomFloat32(r)),
+				Amount: float64(randomFloat32(r)),
+			}
+			if !isIntradayPeriod(period) {
+				records[i].Date = ts
+			}
+			ts += 24 * 60 * 60 * 1000
+		}
+
+		req := NewPeriodDataReq(4, entity.ParseSecurityUnsafe("600000.SH"), period, 0, uint16(len(records)))
+		err, result := NewPeriodDataParser(req, EncodeResp(4, CMD_PERIOD_DATA, EncodePeriodData(period, records), compress)).Parse()
+		return err == nil && reflect.DeepEqual(result, records)
+	}
+	if err := quick.Check(f, quickConfig); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInfoExRoundTrip(t *testing.T) {
+	f := func(seed int64, compress bool) bool {
+		r := rand.New(rand.NewSource(seed))
+		infoEx := map[string][]*InfoExItem{}
+		req := NewInfoExReq(5)
+		for i := r.Intn(20); i > 0; i-- {
+			security := randomSecurity(r)
+			items := []*InfoExItem{}
+			for j := r.Intn(10); j > 0; j-- {
+				// 除以10后能够精确还原的值
+				items = append(items, &InfoExItem{
+					Date: uint32(19900101 + r.Intn(30) * 10000),
+					Bonus: float32(r.Intn(1000)) / 8,
+					RationedSharePrice: randomFloat32(r),
+					DeliveredShares: float32(r.Intn(1000)) / 4,
+					RationedShares: float32(r.Intn(1000)) / 2,
+				})
+			}
+			infoEx[security] = items
+			req.AddCode(entity.ParseSecurityUnsafe(security))
+		}
+
+		err, result := NewInfoExParser(req, EncodeResp(5, CMD_INFO_EX, EncodeInfoExData(infoEx), compress)).Parse()
+		return err == nil && reflect.DeepEqual(result, infoEx)
+	}
+	if err := quick.Check(f, quickConfig); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInfoExEventsRoundTrip(t *testing.T) {
+	f := func(seed int64, compress bool) bool {
+		r := rand.New(rand.NewSource(seed))
+		infoEx := map[string][]*InfoExEvent{}
+		req := NewInfoExReq(5)
+		for i := r.Intn(20); i > 0; i-- {
+			security := randomSecurity(r)
+			events := []*InfoExEvent{}
+			for j := r.Intn(10); j > 0; j-- {
+				event := &InfoExEvent{
+					Date: uint32(19900101 + r.Intn(30) * 10000),
+					Category: byte(r.Intn(16)),
+				}
+				switch event.Category {
+				case XDXR_DIVIDEND:
+					event.Bonus = float32(r.Intn(1000)) / 8
+					event.RationedSharePrice = randomFloat32(r)
+				case XDXR_SPLIT, XDXR_NON_TRADABLE_SPLIT:
+					event.SplitRatio = randomFloat32(r)
+				case XDXR_CALL_WARRANT, XDXR_PUT_WARRANT:
+					event.StrikePrice = randomFloat32(r)
+					event.WarrantShares = randomFloat32(r)
+				default:
+					event.FloatSharesBefore = randomFloat32(r)
+					event.TotalSharesBefore = randomFloat32(r)
+					event.FloatSharesAfter = randomFloat32(r)
+					event.TotalSharesAfter = randomFloat32(r)
+				}
+				events = append(events, event)
+			}
+			infoEx[security] = events
+			req.AddCode(entity.ParseSecurityUnsafe(security))
+		}
+
+		err, result := NewInfoExParser(req, EncodeResp(5, CMD_INFO_EX, EncodeInfoExEvents(infoEx), compress)).ParseEvents()
+		return err == nil && reflect.DeepEqual(result, infoEx)
+	}
+	if err := quick.Check(f, quickConfig); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestXdxrCategoryName(t *testing.T) {
+	if GetXdxrCategoryName(XDXR_DIVIDEND) != "除权除息" || GetXdxrCategoryName(XDXR_PUT_WARRANT) != "送认沽权证" || GetXdxrCategoryName(0) != "未知类别0" {
+		t.Fatal("bad category name")
+	}
+	if (&InfoExEvent{Category: XDXR_DIVIDEND}).IsShareChange() || !(&InfoExEvent{Category: XDXR_CAPITAL_CHANGE}).IsShareChange() {
+		t.Fatal("bad share change")
+	}
+}
+
+func TestFinanceRoundTrip(t *testing.T) {
+	f := func(seed int64, compress bool) bool {
+		r := rand.New(rand.NewSource(seed))
+		finances := map[string]*Finance{}
+		req := NewFinanceReq(6)
+		for i := r.Intn(20); i > 0; i-- {
+			finance := &Finance{}
+			value := reflect.ValueOf(finance).Elem()
+			for j := 0; j < value.NumField(); j++ {
+				value.Field(j).SetFloat(float64(randomFloat32(r)))
+			}
+			security := randomSecurity(r)
+			finances[security] = finance
+			req.AddCode(entity.ParseSecurityUnsafe(security))
+		}
+
+		err, result := NewFinanceParser(req, EncodeResp(6, CMD_FINANCE, EncodeFinanceData(finances), compress)).Parse()
+		return err == nil && reflect.DeepEqual(result, finances)
+	}
+	if err := quick.Check(f, quickConfig); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRawDataRoundTrip(t *testing.T) {
+	f := func(data []byte, count uint16, compress bool) bool {
+		err, length, fileData := NewGetFileDataParser(NewGetFileDataReq(7, "zhb.zip", 0, 30000), EncodeResp(7, CMD_GET_FILE_DATA, EncodeFileData(data), compress)).Parse()
+		if err != nil || int(length) != len(data) || !bytes.Equal(fileData, data) {
+			return false
+		}
+
+		err, fileLength := NewGetFileLenParser(NewGetFileLenReq(8, "zhb.zip"), EncodeResp(8, CMD_GET_FILE_LEN, EncodeFileLenData(uint32(len(data))), compress)).Parse()
+		if err != nil || int(fileLength) != len(data) {
+			return false
+		}
+
+		err, n, names := NewNamesParser(NewNamesReq(9, 0, 0), EncodeResp(9, CMD_NAMES, EncodeNamesData(count, data), compress)).Parse()
+		if err != nil || n != count || !bytes.Equal(names, data) {
+			return false
+		}
+
+		err, namesLen := NewNamesLenParser(NewNamesLenReq(10, 0), EncodeResp(10, CMD_NAMES_LEN, EncodeNamesLenData(count), compress)).Parse()
+		if err != nil || namesLen != uint32(count) {
+			return false
+		}
+
+		err, hisData := NewPeriodHisDataParser(&PeriodHisDataReq{Header: Header{SeqId: 11, Cmd: CMD_PERIOD_HIS_DATA}}, EncodeResp(11, CMD_PERIOD_HIS_DATA, EncodePeriodHisData(data), compress)).Parse()
+		return err == nil && bytes.Equal(hisData, data)
+	}
+	if err := quick.Check(f, quickConfig); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSecurityListRoundTrip(t *testing.T) {
+	f := func(seed int64) bool {
+		r := rand.New(rand.NewSource(seed))
+		market := uint16(r.Intn(3))
+		infos := make([]*SecurityInfo, r.Intn(100))
+		for i := range infos {
+			code := fmt.Sprintf("%06d", r.Intn(1000000))
+			name := fmt.Sprintf("N%d", r.Intn(10000000))
+			infos[i] = &SecurityInfo{
+				Code: GetFullCode(byte(market), code),
+				Name: name,
+				Pinyin: name,
+				Block: BlockFromMarketCode(byte(market), code),
+				VolUnit: uint16(r.Intn(1000)),
+				DecimalPoint: byte(r.Intn(4)),
+				PreClose: float64(randomFloat32(r)),
+				Reserved1: r.Uint32(),
+				Reserved2: r.Uint32(),
+			}
+		}
+
+		err, result := ParseSecurityList(market, EncodeSecurityList(infos))
+		return err == nil && reflect.DeepEqual(result, infos)
+	}
+	if err := quick.Check(f, quickConfig); err != nil {
+		t.Fatal(err)
+	}
+
+	if err, _ := ParseSecurityList(MARKET_SZ, make([]byte, SECURITY_RECORD_LEN + 1)); err != ErrIncompleteData {
+		t.Errorf("expected ErrIncompleteData, got %v", err)
+	}
+}
+
+func TestMinuteTimeRoundTrip(t *testing.T) {
+	f := func(seed int64, compress bool) bool {
+		r := rand.New(rand.NewSource(seed))
+		points := make([]MinuteTimePoint, r.Intn(241))
+		for i := range points {
+			points[i] = MinuteTimePoint{
+				Minute: GetTradingMinute(i),
+				Price: uint32(r.Int31()),
+				Volume: uint32(r.Int31()),
+				Reserved: int(r.Int31()) - 1 << 30,
+			}
+		}
+
+		security := entity.ParseSecurityUnsafe(randomSecurity(r))
+		err, result := NewMinuteTimeParser(NewMinuteTimeReq(1, security), EncodeResp(1, CMD_MINUTE_TIME, EncodeMinuteTimeData(points), compress)).Parse()
+		if err != nil || !reflect.DeepEqual(result, points) {
+			return false
+		}
+
+		err, result = NewHisMinuteTimeParser(NewHisMinuteTimeReq(2, 20181102, security), EncodeResp(2, CMD_HIS_MINUTE_TIME, EncodeHisMinuteTimeData(points), compress)).Parse()
+		return err == nil && reflect.DeepEqual(result, points)
+	}
+	if err := quick.Check(f, quickConfig); err != nil {
+		t.Fatal(err)
+	}
+
+	if GetTradingMinute(0) != 9 * 60 + 31 || GetTradingMinute(119) != 11 * 60 + 30 || GetTradingMinute(120) != 13 * 60 + 1 {
+		t.Error("bad trading minute")
+	}
+}
+
+func TestGetTdxPeriod(t *testing.T) {
+	// init忽略tds/period不认识的名称
+	for tdxPeriod, name := range periodNames {
+		if _, ok := periodMap[tdxPeriod]; !ok {
+			t.Errorf("%s: not parsed by tds/period", name)
+		}
+	}
+
+	for tdxPeriod, p := range periodMap {
+		if err, ret := GetTdxPeriod(p); err != nil || ret != tdxPeriod {
+			t.Errorf("%s: expected %d, got %d %v", p.ShortName(), tdxPeriod, ret, err)
+		}
+	}
+}
+
+func TestIndexPeriodDataRoundTrip(t *testing.T) {
+	f := func(seed int64, compress bool) bool {
+		r := rand.New(rand.NewSource(seed))
+		records := make([]IndexRecord, r.Intn(100))
+		plain := make([]entity.Record, len(records))
+		ts := tdxdatasource.DayDateToTimestamp(20180102)
+		for i := range records {
+			price := r.Intn(10000000)
+			records[i].Record = entity.Record{
+				Date: ts + uint64(i) * 24 * 60 * 60 * 1000,
+				Open: float64(price) / 1000,
+				Close: float64(price + r.Intn(20000) - 10000) / 1000,
+				High: float64(price + r.Intn(10000)) / 1000,
+				Low: float64(price - r.Intn(10000)) / 1000,
+				Volume: float64(randomFloat32(r)),
+				Amount: float64(randomFloat32(r)),
+			}
+			records[i].UpCount = uint16(r.Intn(3000))
+			records[i].DownCount = uint16(r.Intn(3000))
+			plain[i] = records[i].Record
+		}
+		data := EncodeResp(1, CMD_PERIOD_DATA, EncodeIndexPeriodData(PERIOD_DAY, records), compress)
+
+		for _, code := range []string{"399001.SZ", "000001.SH"} {
+			req := NewPeriodDataReq(1, entity.ParseSecurityUnsafe(code), PERIOD_DAY, 0, uint16(len(records)))
+			err, result := NewPeriodDataParser(req, data).Parse()
+			if err != nil || !reflect.DeepEqual(result, plain) {
+				return false
+			}
+
+			err, indexResult := NewIndexPeriodDataParser(req, data).ParseIndex()
+			if err != nil || !reflect.DeepEqual(indexResult, records) {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(f, quickConfig); err != nil {
+		t.Fatal(err)
+	}
+}
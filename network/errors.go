@@ -0,0 +1,138 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+var (
+	ErrIncompleteData = errors.New("incomplete data")
+	ErrBadSeqId = errors.New("bad seq id")
+	ErrBadCmd = errors.New("bad cmd")
+	ErrBadData = errors.New("bad data")
+	ErrBadStockCode = errors.New("bad stock code")
+	ErrNoStockCode = errors.New("no stock code found")
+	ErrBadPeriod = errors.New("bad period")
+	ErrAPIClosed = errors.New("api closed")
+	ErrBadFrame = errors.New("bad frame")
+	ErrDecompress = errors.New("decompress fail")
+	ErrUnknownCmd = errors.New("unknown cmd")
+	ErrBadAdjustType = errors.New("bad adjust type")
+	ErrBadBarSize = errors.New("bad bar size")
+	ErrTransChanged = errors.New("transactions changed while paging")
+	ErrUnknownBlock = errors.New("unknown block")
+	ErrBadReq = errors.New("request does not match the parser")
+	ErrTransTruncated = errors.New("transactions truncated by MaxTransPages")
+)
+
+// ProtocolError describes a response which does not match its request or can not be decoded.
+type ProtocolError struct {
+	Cmd uint16
+	SeqId uint32
+	Security string
+	Data []byte			// 出错的数据
+	Err error
+}
+
+func (this *ProtocolError) Error() string {
+	msg := fmt.Sprintf("%s, cmd: 0x%04x, seq id: %d", this.Err.Error(), this.Cmd, this.SeqId)
+	if this.Security != "" {
+		msg += ", security: " + this.Security
+	}
+	return msg
+}
+
+func (this *ProtocolError) Unwrap() error {
+	return this.Err
+}
+
+// DecompressError is returned when a zlib compressed response can not be uncompressed,
+// or its uncompressed size does not equal Len1 in the header.
+type DecompressError struct {
+	Len uint16
+	Len1 uint16
+	Size int			// 实际解压后的长度
+	Err error
+}
+
+func (this *DecompressError) Error() string {
+	msg := fmt.Sprintf("%s, len: %d, len1: %d, size: %d", ErrDecompress.Error(), this.Len, this.Len1, this.Size)
+	if this.Err != nil {
+		msg += ", error: " + this.Err.Error()
+	}
+	return msg
+}
+
+func (this *DecompressError) Is(target error) bool {
+	return target == ErrDecompress || target == ErrBadData
+}
+
+func (this *DecompressError) Unwrap() error {
+	return this.Err
+}
+
+func newProtocolError(req Request, err error, data []byte) *ProtocolError {
+	result := &ProtocolError{
+		Cmd: req.GetCmd(),
+		SeqId: req.GetSeqId(),
+		Data: data,
+		Err: err,
+	}
+
+	switch r := req.(type) {
+	case *InstantTransReq:
+		result.Security = GetFullCode(byte(r.Location), r.StockCode)
+	case *HisTransReq:
+		result.Security = GetFullCode(byte(r.Location), r.StockCode)
+	case *PeriodDataReq:
+		result.Security = GetFullCode(byte(r.Location), r.StockCode)
+	case *PeriodHisDataReq:
+		result.Security = GetFullCode(byte(r.Location), r.StockCode)
+	case *MinuteTimeReq:
+		result.Security = GetFullCode(byte(r.Location), r.StockCode)
+	case *HisMinuteTimeReq:
+		result.Security = GetFullCode(r.Location, r.StockCode)
+	}
+	return result
+}
+
+// IsNetworkError reports whether err comes from the connection rather than the data.
+func IsNetworkError(err error) bool {
+	if err == nil || errors.Is(err, ErrDecompress) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, ErrBadFrame) ||
+		errors.Is(err, errMuxConnClosed) || errors.Is(err, errMuxTimeout)
+}
+
+// IsRetryable reports whether sending the same request again may succeed.
+// Network errors and responses out of step with their request are retryable,
+// undecodable data, bad arguments and cancellation are not.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if IsNetworkError(err) {
+		return true
+	}
+
+	return errors.Is(err, ErrIncompleteData) || errors.Is(err, ErrBadSeqId) || errors.Is(err, ErrBadCmd)
+}
+
+func IsFatal(err error) bool {
+	return err != nil && !IsRetryable(err)
+}
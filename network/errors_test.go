@@ -0,0 +1,72 @@
+package network
+
+import (
+	"testing"
+	"encoding/binary"
+	"errors"
+	"io"
+	"context"
+)
+
+func buildResp(seqId uint32, cmd uint16, body []byte) []byte {
+	data := make([]byte, RESP_HEADER_LEN + len(body))
+	copy(data, []byte{0xb1, 0xcb, 0x74, 0x00})
+	binary.LittleEndian.PutUint32(data[5:9], seqId)
+	binary.LittleEndian.PutUint16(data[10:12], cmd)
+	binary.LittleEndian.PutUint16(data[12:14], uint16(len(body)))
+	binary.LittleEndian.PutUint16(data[14:16], uint16(len(body)))
+	copy(data[RESP_HEADER_LEN:], body)
+	return data
+}
+
+func TestProtocolError(t *testing.T) {
+	req := NewHeartBeatReq(3)
+
+	if err := NewHeartBeatParser(req, buildResp(3, CMD_HEART_BEAT, []byte{0, 0})).Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		data []byte
+		target error
+	}{
+		{buildResp(4, CMD_HEART_BEAT, nil), ErrBadSeqId},
+		{buildResp(3, CMD_GET_FILE_DATA, nil), ErrBadCmd},
+		{buildResp(3, CMD_HEART_BEAT, []byte{0, 0})[:RESP_HEADER_LEN + 1], ErrIncompleteData},
+		{[]byte{0xb1, 0xcb}, ErrIncompleteData},
+	}
+
+	for _, c := range cases {
+		err := NewHeartBeatParser(req, c.data).Parse()
+		if !errors.Is(err, c.target) {
+			t.Fatalf("expect %v, got %v", c.target, err)
+		}
+
+		var protoErr *ProtocolError
+		if !errors.As(err, &protoErr) {
+			t.Fatalf("expect ProtocolError, got %T", err)
+		}
+		if protoErr.Cmd != CMD_HEART_BEAT || protoErr.SeqId != 3 {
+			t.Fatalf("bad error fields: %+v", protoErr)
+		}
+		if !IsRetryable(err) {
+			t.Fatalf("expect retryable: %v", err)
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	retryable := []error{io.EOF, io.ErrUnexpectedEOF, errMuxTimeout, &ProtocolError{Err: ErrBadSeqId}}
+	for _, err := range retryable {
+		if !IsRetryable(err) || IsFatal(err) {
+			t.Fatalf("expect retryable: %v", err)
+		}
+	}
+
+	fatal := []error{context.Canceled, context.DeadlineExceeded, ErrBadData, ErrBadPeriod, &ProtocolError{Err: ErrBadStockCode}}
+	for _, err := range fatal {
+		if IsRetryable(err) || !IsFatal(err) {
+			t.Fatalf("expect fatal: %v", err)
+		}
+	}
+}
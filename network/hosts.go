@@ -0,0 +1,120 @@
+package network
+
+import (
+	"context"
+	"encoding/hex"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_PORT = "7709"
+	PROBE_TIMEOUT = 3 * time.Second
+)
+
+// Connection prolog, every new connection must send them first
+var handshakeReqs = []string{
+	"0c0218940001030003000d0001",
+	"0c031899000120002000db0fb3a4bdadd6a4c8af0000009a993141090000000000000000000000000003",
+}
+
+type HostStat struct {
+	Host string
+	Latency time.Duration
+	Err error
+}
+
+func handshake(conn net.Conn) error {
+	for _, reqHex := range handshakeReqs {
+		reqData, _ := hex.DecodeString(reqHex)
+
+		_, err := conn.Write(reqData)
+		if err != nil {
+			return err
+		}
+
+		err, _ = ReadResp(conn)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithDefaultPort appends the default quote server port if host has none.
+func WithDefaultPort(host string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, DEFAULT_PORT)
+}
+
+// ParseHosts splits a comma separated host list, e.g. "1.2.3.4,5.6.7.8:7709".
+func ParseHosts(hosts string) []string {
+	result := []string{}
+	for _, host := range strings.Split(hosts, ",") {
+		host = strings.TrimSpace(host)
+		if host == "" {
+			continue
+		}
+		result = append(result, WithDefaultPort(host))
+	}
+	return result
+}
+
+// ProbeHost connects to host and measures how long the handshake takes.
+func ProbeHost(host string, timeout time.Duration) HostStat {
+	return ProbeHostWithDialer(&net.Dialer{}, host, timeout)
+}
+
+func ProbeHostWithDialer(dialer Dialer, host string, timeout time.Duration) HostStat {
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return HostStat{Host: host, Err: err}
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(start.Add(timeout))
+	err = handshake(conn)
+	if err != nil {
+		return HostStat{Host: host, Err: err}
+	}
+
+	return HostStat{Host: host, Latency: time.Since(start)}
+}
+
+// RankHosts probes all hosts concurrently. Reachable hosts come first ordered by latency,
+// unreachable ones follow in their original order.
+func RankHosts(hosts []string, timeout time.Duration) []HostStat {
+	return RankHostsWithDialer(&net.Dialer{}, hosts, timeout)
+}
+
+func RankHostsWithDialer(dialer Dialer, hosts []string, timeout time.Duration) []HostStat {
+	result := make([]HostStat, len(hosts))
+
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			result[i] = ProbeHostWithDialer(dialer, host, timeout)
+		}(i, host)
+	}
+	wg.Wait()
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if (result[i].Err == nil) != (result[j].Err == nil) {
+			return result[i].Err == nil
+		}
+		return result[i].Err == nil && result[i].Latency < result[j].Latency
+	})
+
+	return result
+}
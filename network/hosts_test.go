@@ -0,0 +1,36 @@
+package network
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithDefaultPort(t *testing.T) {
+	cases := map[string]string{
+		"1.2.3.4": "1.2.3.4:7709",
+		"1.2.3.4:7711": "1.2.3.4:7711",
+		"example.com": "example.com:7709",
+		"::1": "[::1]:7709",
+		"[::1]:80": "[::1]:80",
+	}
+
+	for host, expected := range cases {
+		if ret := WithDefaultPort(host); ret != expected {
+			t.Errorf("%s: expected %s, got %s", host, expected, ret)
+		}
+	}
+}
+
+func TestParseHosts(t *testing.T) {
+	cases := map[string][]string{
+		"": {},
+		"1.2.3.4": {"1.2.3.4:7709"},
+		" 1.2.3.4 , 5.6.7.8:7711,,": {"1.2.3.4:7709", "5.6.7.8:7711"},
+	}
+
+	for hosts, expected := range cases {
+		if ret := ParseHosts(hosts); !reflect.DeepEqual(ret, expected) {
+			t.Errorf("%q: expected %v, got %v", hosts, expected, ret)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package network
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"time"
+	"github.com/z-ray/log"
+)
+
+const (
+	MUX_CONN_COUNT = 2
+)
+
+var errMuxConnClosed = errors.New("mux connection closed")
+var errMuxTimeout = errors.New("mux request timeout")
+
+type muxResult struct {
+	err error
+	data []byte
+}
+
+// muxConn keeps many requests in flight on one connection, responses are dispatched by SeqId.
+type muxConn struct {
+	conn net.Conn
+
+	writeLock sync.Mutex
+
+	lock sync.Mutex
+	pending map[uint32]chan muxResult
+	err error
+	lastRead time.Time
+}
+
+func newMuxConn(conn net.Conn) *muxConn {
+	this := &muxConn{
+		conn: conn,
+		pending: map[uint32]chan muxResult{},
+	}
+	go this.readLoop()
+	return this
+}
+
+func (this *muxConn) readLoop() {
+	for {
+		err, data := ReadResp(this.conn)
+		if err != nil {
+			this.fail(err)
+			return
+		}
+
+		seqId := binary.LittleEndian.Uint32(data[5:9])
+
+		this.lock.Lock()
+		this.lastRead = time.Now()
+		ch, ok := this.pending[seqId]
+		delete(this.pending, seqId)
+		this.lock.Unlock()
+
+		if !ok {
+			log.Errorf("muxConn.readLoop - no request waiting for seq id %d", seqId)
+			continue
+		}
+		ch <- muxResult{data: data}
+	}
+}
+
+// fail closes the connection and wakes up all waiting requests with err.
+func (this *muxConn) fail(err error) {
+	this.lock.Lock()
+	if this.err != nil {
+		this.lock.Unlock()
+		return
+	}
+	this.err = err
+	pending := this.pending
+	this.pending = map[uint32]chan muxResult{}
+	this.lock.Unlock()
+
+	this.conn.Close()
+	for _, ch := range pending {
+		ch <- muxResult{err: err}
+	}
+}
+
+func (this *muxConn) isAlive() bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.err == nil
+}
+
+func (this *muxConn) Close() {
+	this.fail(errMuxConnClosed)
+}
+
+func (this *muxConn) roundTrip(ctx context.Context, data []byte, writeDeadline, readDeadline time.Time) (error, []byte) {
+	seqId := binary.LittleEndian.Uint32(data[1:5])
+	ch := make(chan muxResult, 1)
+
+	this.lock.Lock()
+	if this.err != nil {
+		err := this.err
+		this.lock.Unlock()
+		return err, nil
+	}
+	if _, ok := this.pending[seqId]; ok {
+		this.lock.Unlock()
+		return errors.New("duplicated seq id"), nil
+	}
+	this.pending[seqId] = ch
+	this.lock.Unlock()
+
+	this.writeLock.Lock()
+	this.conn.SetWriteDeadline(writeDeadline)
+	_, err := this.conn.Write(data)
+	sent := time.Now()
+	this.writeLock.Unlock()
+	if err != nil {
+		this.fail(err)
+		return err, nil
+	}
+
+	var timeoutCh <-chan time.Time
+	if !readDeadline.IsZero() {
+		timer := time.NewTimer(time.Until(readDeadline))
+		defer timer.Stop()
+		timeoutCh = timer.C
+	}
+
+	select {
+	case r := <-ch:
+		return r.err, r.data
+	case <-ctx.Done():
+		this.cancel(seqId)
+		return ctx.Err(), nil
+	case <-timeoutCh:
+		// Nothing read since the request was sent, the connection is half open and fails,
+		// otherwise only this request is given up
+		this.cancel(seqId)
+		if !this.readSince(sent) {
+			this.fail(errMuxTimeout)
+		}
+		return errMuxTimeout, nil
+	}
+}
+
+func (this *muxConn) readSince(t time.Time) bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return !this.lastRead.Before(t)
+}
+
+func (this *muxConn) cancel(seqId uint32) {
+	this.lock.Lock()
+	delete(this.pending, seqId)
+	this.lock.Unlock()
+}
+
+// SetMultiplexEnabled makes requests share a few connections with several requests in flight on each,
+// instead of taking one pooled connection per request.
+func (this *API) SetMultiplexEnabled(enabled bool) {
+	this.muxLock.Lock()
+	defer this.muxLock.Unlock()
+
+	this.muxEnabled = enabled
+	if !enabled {
+		this.closeMuxConns()
+	}
+}
+
+func (this *API) closeMuxConns() {
+	this.muxGen++
+	for i, c := range this.muxConns {
+		if c != nil {
+			c.Close()
+		}
+		this.muxConns[i] = nil
+	}
+}
+
+func (this *API) resetMuxConns() {
+	this.muxLock.Lock()
+	defer this.muxLock.Unlock()
+
+	this.closeMuxConns()
+}
+
+func (this *API) isMuxEnabled() bool {
+	this.muxLock.Lock()
+	defer this.muxLock.Unlock()
+	return this.muxEnabled
+}
+
+func (this *API) getMuxConn(ctx context.Context) (error, *muxConn) {
+	for {
+		this.muxLock.Lock()
+		if !this.muxEnabled {
+			this.muxLock.Unlock()
+			return errMuxConnClosed, nil
+		}
+		if len(this.muxConns) == 0 {
+			this.muxConns = make([]*muxConn, this.options.MuxConns)
+		}
+
+		this.muxIndex = (this.muxIndex + 1) % len(this.muxConns)
+		index := this.muxIndex
+		gen := this.muxGen
+		c := this.muxConns[index]
+		this.muxLock.Unlock()
+
+		if c != nil && c.isAlive() {
+			return nil, c
+		}
+
+		// Dial without muxLock, requests on the other connections are not blocked
+		conn, err := this.dialHost(ctx, this.CurrentHost())
+		if err != nil {
+			return err, nil
+		}
+		newConn := newMuxConn(conn)
+
+		this.muxLock.Lock()
+		if gen != this.muxGen {
+			// Reset during dialing, the new connection may be to the old host
+			this.muxLock.Unlock()
+			newConn.Close()
+			continue
+		}
+		if current := this.muxConns[index]; current != c && current != nil && current.isAlive() {
+			// Installed by another request
+			this.muxLock.Unlock()
+			newConn.Close()
+			return nil, current
+		}
+		this.muxConns[index] = newConn
+		this.muxLock.Unlock()
+		return nil, newConn
+	}
+}
+
+func (this *API) sendReqMux(ctx context.Context, data []byte) (error, []byte) {
+	err, c := this.getMuxConn(ctx)
+	if err != nil {
+		return err, nil
+	}
+
+	err, respData := c.roundTrip(ctx, data, this.writeDeadline(ctx), this.readDeadline(ctx))
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err(), nil
+	}
+	return err, respData
+}
@@ -0,0 +1,109 @@
+package network
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+type Options struct {
+	Hosts []string
+
+	InitialCap int					// 初始连接数
+	MaxCap int						// 最大空闲连接数, 多出的连接归还时关闭
+	MaxConns int					// 最大连接数, 包括空闲和使用中的, 达到后请求等待连接归还. 0表示不限制
+
+	DialTimeout time.Duration		// 包括Dialer和握手, 0表示不超时
+	ReadTimeout time.Duration
+	WriteTimeout time.Duration
+
+	IdleTimeout time.Duration		// 空闲超过此时间的连接被丢弃, 0表示不丢弃
+	MaxLifetime time.Duration		// 连接最长使用时间, 0表示不限制
+
+	MuxConns int					// 多路复用的连接数
+
+	Dialer Dialer					// nil表示直连, 握手仍在Dialer返回的连接上进行, DialTimeout通过ctx传给Dialer
+}
+
+func DefaultOptions() *Options {
+	return &Options{
+		InitialCap: 5,
+		MaxCap: 5,
+		DialTimeout: 10 * time.Second,
+		ReadTimeout: 10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		MuxConns: MUX_CONN_COUNT,
+	}
+}
+
+type PoolStats struct {
+	InUse int64
+	Idle int
+	Created int64				// 连接池新建的连接数, 不包括多路复用的连接
+	Discarded int64				// 出错后被markConnUnusable丢弃
+	Expired int64				// 因IdleTimeout或MaxLifetime丢弃
+}
+
+type poolCounters struct {
+	inUse int64
+	created int64
+	discarded int64
+	expired int64
+}
+
+// trackedConn remembers when a pooled connection was created and last returned.
+type trackedConn struct {
+	net.Conn
+	createdAt time.Time
+	lastUsed atomic.Value
+}
+
+func newTrackedConn(conn net.Conn) *trackedConn {
+	now := time.Now()
+	result := &trackedConn{Conn: conn, createdAt: now}
+	result.lastUsed.Store(now)
+	return result
+}
+
+func trackedConnOf(conn net.Conn) *trackedConn {
+	if poolConn, ok := conn.(*pooledConn); ok {
+		conn = poolConn.Conn
+	}
+	result, _ := conn.(*trackedConn)
+	return result
+}
+
+func (this *API) isConnExpired(conn net.Conn) bool {
+	tc := trackedConnOf(conn)
+	if tc == nil {
+		return false
+	}
+
+	now := time.Now()
+	if this.options.MaxLifetime > 0 && now.Sub(tc.createdAt) > this.options.MaxLifetime {
+		return true
+	}
+	if this.options.IdleTimeout > 0 && now.Sub(tc.lastUsed.Load().(time.Time)) > this.options.IdleTimeout {
+		return true
+	}
+	return false
+}
+
+func (this *API) touchConn(conn net.Conn) {
+	if tc := trackedConnOf(conn); tc != nil {
+		tc.lastUsed.Store(time.Now())
+	}
+}
+
+func (this *API) PoolStats() PoolStats {
+	stats := PoolStats{
+		InUse: atomic.LoadInt64(&this.counters.inUse),
+		Created: atomic.LoadInt64(&this.counters.created),
+		Discarded: atomic.LoadInt64(&this.counters.discarded),
+		Expired: atomic.LoadInt64(&this.counters.expired),
+	}
+	if p := this.getPool(); p != nil {
+		stats.Idle = p.Len()
+	}
+	return stats
+}
@@ -0,0 +1,43 @@
+package network
+
+import (
+	"testing"
+)
+
+func TestOptionsValidation(t *testing.T) {
+	cases := []struct{
+		initialCap, maxCap, maxConns int
+		ok bool
+	}{
+		{0, 5, 0, true},
+		{0, 5, 5, true},
+		{0, 5, 10, true},
+		{0, 0, 0, false},
+		{-1, 5, 0, false},
+		{6, 5, 0, false},
+		{0, 5, 4, false},
+		{0, 5, -1, false},
+	}
+
+	for _, c := range cases {
+		options := DefaultOptions()
+		// InitialCap为0时不连接服务器
+		options.Hosts = []string{"127.0.0.1:1"}
+		options.InitialCap = c.initialCap
+		options.MaxCap = c.maxCap
+		options.MaxConns = c.maxConns
+
+		err, api := CreateAPIWithOptions(options)
+		if (err == nil) != c.ok {
+			t.Errorf("%+v: unexpected error %v", c, err)
+		}
+		if api != nil {
+			api.Cleanup()
+		}
+	}
+
+	err, _ := CreateAPIWithOptions(&Options{MaxCap: 5})
+	if err == nil {
+		t.Error("expect error without hosts")
+	}
+}
@@ -0,0 +1,104 @@
+package network
+
+// GB2312一级汉字按拼音排序，每个首字母对应的第一个汉字编码
+var pinyinBounds = []struct {
+	code uint16
+	letter byte
+}{
+	{0xb0a1, 'A'}, {0xb0c5, 'B'}, {0xb2c1, 'C'}, {0xb4ee, 'D'}, {0xb6ea, 'E'},
+	{0xb7a2, 'F'}, {0xb8c1, 'G'}, {0xb9fe, 'H'}, {0xbbf7, 'J'}, {0xbfa6, 'K'},
+	{0xc0ac, 'L'}, {0xc2e8, 'M'}, {0xc4c3, 'N'}, {0xc5b6, 'O'}, {0xc5be, 'P'},
+	{0xc6da, 'Q'}, {0xc8bb, 'R'}, {0xc8f6, 'S'}, {0xcbfa, 'T'}, {0xcdda, 'W'},
+	{0xcef4, 'X'}, {0xd1b9, 'Y'}, {0xd4d1, 'Z'},
+}
+
+const pinyinLevel1End = 0xd7f9
+
+// 证券名称中常见的非一级汉字
+var pinyinLevel2 = map[uint16]byte{
+	0xf6ce: 'X',		// 鑫
+	0xeac9: 'S',		// 晟
+	0xeabb: 'H',		// 昊
+	0xeccf: 'Y',		// 煜
+	0xeeda: 'Y',		// 钰
+	0xe7f9: 'Q',		// 琦
+	0xab68: 'Y',		// 玥
+}
+
+// 多音字按词取读音, 一级汉字表按其中一个读音排序, 如行排在X
+var pinyinPhrases = []struct {
+	phrase string
+	initials string
+}{
+	{"\xd2\xf8\xd0\xd0", "YH"},		// 银行
+	{"\xd0\xd0\xd2\xb5", "HY"},		// 行业
+	{"\xd6\xd8\xc7\xec", "CQ"},		// 重庆
+	{"\xce\xf7\xb2\xd8", "XZ"},		// 西藏
+	{"\xb4\xf3\xcf\xc3", "DS"},		// 大厦
+	{"\xb3\xc9\xb3\xa4", "CZ"},		// 成长
+}
+
+func pinyinPhrase(name []byte) (string, int) {
+	for _, p := range pinyinPhrases {
+		if len(name) >= len(p.phrase) && string(name[:len(p.phrase)]) == p.phrase {
+			return p.initials, len(p.phrase)
+		}
+	}
+	return "", 0
+}
+
+func pinyinInitial(code uint16) byte {
+	if code >= pinyinBounds[0].code && code <= pinyinLevel1End {
+		letter := pinyinBounds[0].letter
+		for _, bound := range pinyinBounds {
+			if code < bound.code {
+				break
+			}
+			letter = bound.letter
+		}
+		return letter
+	}
+
+	switch {
+	case code >= 0xa3c1 && code <= 0xa3da:		// 全角大写字母
+		return byte(code - 0xa3c1) + 'A'
+	case code >= 0xa3e1 && code <= 0xa3fa:		// 全角小写字母
+		return byte(code - 0xa3e1) + 'A'
+	case code >= 0xa3b0 && code <= 0xa3b9:		// 全角数字
+		return byte(code - 0xa3b0) + '0'
+	}
+	return pinyinLevel2[code]
+}
+
+// GetPinyinInitials returns the pinyin abbreviation of a GBK encoded name, e.g. ZGPA for 中国平安.
+// ASCII letters and digits are kept, other symbols and unknown characters are dropped.
+// Polyphones are read by the phrases in pinyinPhrases, e.g. YH for 银行.
+func GetPinyinInitials(name []byte) string {
+	result := make([]byte, 0, len(name))
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c < 0x80 {
+			switch {
+			case c >= 'a' && c <= 'z':
+				result = append(result, c - 'a' + 'A')
+			case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+				result = append(result, c)
+			}
+			continue
+		}
+
+		if i + 1 >= len(name) {
+			break
+		}
+		if initials, n := pinyinPhrase(name[i:]); n > 0 {
+			result = append(result, initials...)
+			i += n - 1
+			continue
+		}
+		if letter := pinyinInitial(uint16(c) << 8 | uint16(name[i + 1])); letter != 0 {
+			result = append(result, letter)
+		}
+		i++
+	}
+	return string(result)
+}
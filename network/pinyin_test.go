@@ -0,0 +1,46 @@
+package network
+
+import (
+	"testing"
+	"encoding/hex"
+)
+
+func TestGetPinyinInitials(t *testing.T) {
+	cases := map[string]string{
+		"d6d0b9fac6bdb0b2": "ZGPA",			// 中国平安
+		"cdf2bfc6a3c1": "WKA",				// 万科Ａ
+		"2a5354bfb5c3c0": "STKM",			// *ST康美
+		"f6cec6bd": "XP",					// 鑫平
+		"b0a1d7f9": "AZ",					// 啊座
+		"d6d0": "Z",
+		"d6": "",
+	}
+
+	for nameHex, expected := range cases {
+		name, _ := hex.DecodeString(nameHex)
+		if ret := GetPinyinInitials(name); ret != expected {
+			t.Errorf("%s: expected %s, got %s", nameHex, expected, ret)
+		}
+	}
+}
+
+func TestGetPinyinInitialsPolyphone(t *testing.T) {
+	cases := map[string]string{
+		"b9a4c9ccd2f8d0d0": "GSYH",			// 工商银行
+		"d2f8d0d0d2b5": "YHY",				// 银行业
+		"d6d8c7ecc6a1bec6": "CQPJ",			// 重庆啤酒
+		"cef7b2d8d2a9d2b5": "XZYY",			// 西藏药业
+		"c9ccd2b5b4f3cfc3": "SYDS",			// 商业大厦
+		"cfc3c3c5b9fac3b3": "XMGM",			// 厦门国贸
+		"d6d8b9a4": "ZG",					// 重工
+		"d0d0": "X",
+		"d2f8": "Y",
+	}
+
+	for nameHex, expected := range cases {
+		name, _ := hex.DecodeString(nameHex)
+		if ret := GetPinyinInitials(name); ret != expected {
+			t.Errorf("%s: expected %s, got %s", nameHex, expected, ret)
+		}
+	}
+}
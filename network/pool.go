@@ -0,0 +1,186 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+var ErrPoolClosed = errors.New("pool closed")
+
+// connPool keeps the idle connections of one host, like the channel pool of fatih/pool
+// but the idle connections can be taken without dialing, and the total connections can be limited.
+type connPool struct {
+	lock sync.Mutex
+	idle chan net.Conn				// Close后为nil
+	tokens chan struct{}			// 每个打开的连接占一个, nil表示不限制
+	factory func(ctx context.Context) (net.Conn, error)
+}
+
+// newConnPool keeps at most maxIdle idle connections, maxConns limits the idle and the borrowed ones
+// together, 0 means no limit.
+func newConnPool(initialCap, maxIdle, maxConns int, factory func(ctx context.Context) (net.Conn, error)) (*connPool, error) {
+	if initialCap < 0 || maxIdle <= 0 || initialCap > maxIdle || maxConns < 0 || (maxConns > 0 && maxConns < maxIdle) {
+		return nil, errors.New("bad pool capacity")
+	}
+
+	this := &connPool{idle: make(chan net.Conn, maxIdle), factory: factory}
+	if maxConns > 0 {
+		this.tokens = make(chan struct{}, maxConns)
+	}
+	for i := 0; i < initialCap; i++ {
+		this.acquire()
+		conn, err := factory(context.Background())
+		if err != nil {
+			this.release()
+			this.Close()
+			return nil, fmt.Errorf("factory is not able to fill the pool: %w", err)
+		}
+		this.idle <- conn
+	}
+	return this, nil
+}
+
+func (this *connPool) acquire() {
+	if this.tokens != nil {
+		this.tokens <- struct{}{}
+	}
+}
+
+func (this *connPool) release() {
+	if this.tokens != nil {
+		<-this.tokens
+	}
+}
+
+// closeConn closes a connection opened by the pool and frees its slot.
+func (this *connPool) closeConn(conn net.Conn) error {
+	err := conn.Close()
+	this.release()
+	return err
+}
+
+func (this *connPool) getIdle() chan net.Conn {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.idle
+}
+
+func (this *connPool) wrap(conn net.Conn) net.Conn {
+	return &pooledConn{Conn: conn, pool: this}
+}
+
+// Get returns an idle connection, or dials a new one with ctx if there is none. It waits for a connection
+// to be returned or closed if the pool is full.
+func (this *connPool) Get(ctx context.Context) (net.Conn, error) {
+	if conn, err := this.TryGet(); conn != nil || err != nil {
+		return conn, err
+	}
+
+	if this.tokens != nil {
+		idle := this.getIdle()
+		if idle == nil {
+			return nil, ErrPoolClosed
+		}
+
+		select {
+		case conn := <-idle:
+			if conn == nil {
+				return nil, ErrPoolClosed
+			}
+			return this.wrap(conn), nil
+		case this.tokens <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	conn, err := this.factory(ctx)
+	if err != nil {
+		this.release()
+		return nil, err
+	}
+	return this.wrap(conn), nil
+}
+
+// TryGet returns an idle connection, or nil if there is none.
+func (this *connPool) TryGet() (net.Conn, error) {
+	idle := this.getIdle()
+	if idle == nil {
+		return nil, ErrPoolClosed
+	}
+
+	select {
+	case conn := <-idle:
+		if conn == nil {
+			return nil, ErrPoolClosed
+		}
+		return this.wrap(conn), nil
+	default:
+		return nil, nil
+	}
+}
+
+// put returns conn to the pool, conn is closed if the pool is full or closed.
+func (this *connPool) put(conn net.Conn) error {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	if this.idle == nil {
+		return this.closeConn(conn)
+	}
+
+	select {
+	case this.idle <- conn:
+		return nil
+	default:
+		return this.closeConn(conn)
+	}
+}
+
+func (this *connPool) Len() int {
+	return len(this.getIdle())
+}
+
+func (this *connPool) Close() {
+	this.lock.Lock()
+	idle := this.idle
+	this.idle = nil
+	this.lock.Unlock()
+
+	if idle == nil {
+		return
+	}
+
+	close(idle)
+	for conn := range idle {
+		this.closeConn(conn)
+	}
+}
+
+// pooledConn returns the connection to the pool on Close unless it is marked unusable.
+type pooledConn struct {
+	net.Conn
+	pool *connPool
+
+	lock sync.RWMutex
+	unusable bool
+}
+
+func (this *pooledConn) Close() error {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+
+	if this.unusable {
+		return this.pool.closeConn(this.Conn)
+	}
+	return this.pool.put(this.Conn)
+}
+
+func (this *pooledConn) MarkUnusable() {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	this.unusable = true
+}
@@ -0,0 +1,96 @@
+package network
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func pipeFactory(created *int) func(ctx context.Context) (net.Conn, error) {
+	return func(ctx context.Context) (net.Conn, error) {
+		*created++
+		conn, _ := net.Pipe()
+		return conn, nil
+	}
+}
+
+func TestConnPoolMaxConns(t *testing.T) {
+	created := 0
+	p, err := newConnPool(1, 1, 2, pipeFactory(&created))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	c1, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != 2 {
+		t.Fatalf("expect 2 connections, got %d", created)
+	}
+
+	// 连接数已满, 等待归还
+	ctx, cancel := context.WithTimeout(context.Background(), 50 * time.Millisecond)
+	defer cancel()
+	if _, err := p.Get(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expect deadline exceeded, got %v", err)
+	}
+
+	c1.Close()
+	c3, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c3.(*pooledConn).Conn != c1.(*pooledConn).Conn || created != 2 {
+		t.Fatal("expect the returned connection")
+	}
+
+	// 丢弃的连接释放名额
+	c2.(*pooledConn).MarkUnusable()
+	c2.Close()
+	c4, err := p.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != 3 {
+		t.Fatalf("expect a new connection, got %d", created)
+	}
+
+	// 空闲已满时归还的连接被关闭并释放名额
+	c3.Close()
+	c4.Close()
+	if p.Len() != 1 || len(p.tokens) != 1 {
+		t.Fatalf("expect 1 idle and 1 open connection, got %d and %d", p.Len(), len(p.tokens))
+	}
+}
+
+func TestConnPoolTryGet(t *testing.T) {
+	created := 0
+	p, err := newConnPool(1, 2, 0, pipeFactory(&created))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := p.TryGet()
+	if conn == nil || err != nil {
+		t.Fatalf("expect the idle connection, got %v", err)
+	}
+	if conn, err := p.TryGet(); conn != nil || err != nil {
+		t.Fatal("TryGet should not dial")
+	}
+	if created != 1 {
+		t.Fatalf("expect 1 connection, got %d", created)
+	}
+	conn.Close()
+
+	p.Close()
+	if _, err := p.TryGet(); err != ErrPoolClosed {
+		t.Fatalf("expect ErrPoolClosed, got %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package network
 import "bytes"
 import (
 	"encoding/binary"
+	"strings"
 	"github.com/stephenlyu/tds/date"
 	"github.com/stephenlyu/tds/entity"
 	"github.com/stephenlyu/tds/period"
@@ -19,11 +20,19 @@ const (
 	CMD_PERIOD_HIS_DATA = 0x0fcd
 	CMD_HIS_TRANS = 0x0fb5
 	CMD_HEART_BEAT = 0x0523
+	CMD_MINUTE_TIME = 0x051d
+	CMD_HIS_MINUTE_TIME = 0x0fb4
 
 	CMD_GET_FILE_LEN = 0x2c5
 	CMD_GET_FILE_DATA = 0x6b9
 )
 
+const (
+	MARKET_SZ = 0
+	MARKET_SH = 1
+	MARKET_BJ = 2
+)
+
 const (
 	BLOCK_SH_A = 0
 	BLOCK_SH_B = 1
@@ -35,8 +44,15 @@ const (
 
 const (
 	PERIOD_MINUTE5 = 0x0000
+	PERIOD_MINUTE15 = 0x0001
+	PERIOD_MINUTE30 = 0x0002
+	PERIOD_HOUR = 0x0003
 	PERIOD_DAY = 0x0004
+	PERIOD_WEEK = 0x0005
+	PERIOD_MONTH = 0x0006
 	PERIOD_MINUTE = 0x0007
+	PERIOD_QUARTER = 0x000a
+	PERIOD_YEAR = 0x000b
 )
 
 var periodMap = map[uint16]period.Period {
@@ -45,6 +61,43 @@ var periodMap = map[uint16]period.Period {
 	PERIOD_DAY: period.PERIOD_D,
 }
 
+// 其他周期在tds/period中的名称
+var periodNames = map[uint16]string {
+	PERIOD_MINUTE15: "M15",
+	PERIOD_MINUTE30: "M30",
+	PERIOD_HOUR: "M60",
+	PERIOD_WEEK: "W1",
+	PERIOD_MONTH: "N1",
+	PERIOD_QUARTER: "Q1",
+	PERIOD_YEAR: "Y1",
+}
+
+func init() {
+	for tdxPeriod, name := range periodNames {
+		if err, p := period.PeriodFromString(name); err == nil {
+			periodMap[tdxPeriod] = p
+		}
+	}
+}
+
+// GetTdxPeriod returns the TDX category code of p.
+func GetTdxPeriod(p period.Period) (error, uint16) {
+	for tdxPeriod, v := range periodMap {
+		if v.ShortName() == p.ShortName() {
+			return nil, tdxPeriod
+		}
+	}
+	return ErrBadPeriod, 0
+}
+
+func isIntradayPeriod(period uint16) bool {
+	switch period {
+	case PERIOD_MINUTE, PERIOD_MINUTE5, PERIOD_MINUTE15, PERIOD_MINUTE30, PERIOD_HOUR:
+		return true
+	}
+	return false
+}
+
 
 type Request interface {
 	GetSeqId() uint32
@@ -82,6 +135,7 @@ type NamesReq struct {
 type NamesLenReq struct {
 	Header
 	Block uint16
+	Date uint32
 }
 
 type InfoExReq struct {
@@ -131,6 +185,22 @@ type PeriodDataReq struct {
 	Unknown2 uint32			// 0
 	Unknown3 uint32			// 0
 	Unknown4 uint16			// 0
+
+	Index bool				// 不在协议中, 强制按指数K线解析响应
+}
+
+type MinuteTimeReq struct {
+	Header
+	Location uint16
+	StockCode string
+	Reserved uint32
+}
+
+type HisMinuteTimeReq struct {
+	Header
+	Date uint32
+	Location byte
+	StockCode string
 }
 
 type PeriodHisDataReq struct {
@@ -142,6 +212,10 @@ type PeriodHisDataReq struct {
 	Period uint16
 }
 
+type HeartBeatReq struct {
+	Header
+}
+
 type GetFileLenReq struct {
 	Header
 	FileName string
@@ -155,21 +229,33 @@ type GetFileDataReq struct {
 }
 
 func MarketLocationFromSecurity(security *entity.Security) byte {
-	if security.Exchange == "SZ"{
-		return 0
+	switch security.Exchange {
+	case "SZ":
+		return MARKET_SZ
+	case "BJ":
+		return MARKET_BJ
 	}
-	return 1
+	return MARKET_SH
 }
 
 func GetFullCode(loc byte, code string) string {
-	if loc == 0 {
+	switch loc {
+	case MARKET_SZ:
 		return code + ".SZ"
+	case MARKET_BJ:
+		return code + ".BJ"
 	}
 	return code + ".SH"
 }
 
+// BlockFromCode guesses the block without the market, codes of different markets overlap,
+// e.g. 000001.SH is an index and 000001.SZ is not, use BlockFromMarketCode if the market is known.
 func BlockFromCode(stockCode string) int {
 	data := []byte(stockCode)
+	if len(data) < 2 {
+		return BLOCK_UNKNOWN
+	}
+
 	switch data[0] {
 	case 0x30:
 		return BLOCK_SZ_A
@@ -183,7 +269,7 @@ func BlockFromCode(stockCode string) int {
 		}
 	case 0x36:
 		return BLOCK_SH_A
-	case 0x99:
+	case 0x39:
 		if data[1] == 0x30 {
 			return BLOCK_SH_B
 		} else {
@@ -194,6 +280,44 @@ func BlockFromCode(stockCode string) int {
 	}
 }
 
+// BlockFromMarketCode returns the block of the code in market, the codes of BJ are never indices.
+func BlockFromMarketCode(market byte, stockCode string) int {
+	hasPrefix := func(prefixes ...string) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(stockCode, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch market {
+	case MARKET_SH:
+		switch {
+		case hasPrefix("000", "999", "880"):
+			return BLOCK_INDEX
+		case hasPrefix("60", "68"):
+			return BLOCK_SH_A
+		case hasPrefix("900"):
+			return BLOCK_SH_B
+		}
+	case MARKET_SZ:
+		switch {
+		case hasPrefix("399"):
+			return BLOCK_INDEX
+		case hasPrefix("00", "30"):
+			return BLOCK_SZ_A
+		case hasPrefix("20"):
+			return BLOCK_SZ_B
+		}
+	}
+	return BLOCK_UNKNOWN
+}
+
+func IsIndexCode(market byte, stockCode string) bool {
+	return BlockFromMarketCode(market, stockCode) == BLOCK_INDEX
+}
+
 func writeUInt16(writer *bytes.Buffer, v uint16) {
 	var int16buf2 [2]byte
 	binary.LittleEndian.PutUint16(int16buf2[:], v)
@@ -210,6 +334,10 @@ func (this *Header) Write(writer *bytes.Buffer) {
 	binary.Write(writer, binary.LittleEndian, *this)
 }
 
+func (this *Header) SetSeqId(seqId uint32) {
+	this.SeqId = seqId
+}
+
 func (this *Header) SetLength(length uint16) {
 	this.Len = length
 	this.Len1 = length
@@ -449,6 +577,7 @@ func NewPeriodDataReq(seqId uint32, security *entity.Security, period uint16, of
 		0,
 		0,
 		0,
+		false,
 	}
 
 	req.Header.Len = req.Size()
@@ -493,6 +622,70 @@ func NewPeriodHisDataReq(seqId uint32, security *entity.Security, period uint16,
 	return req
 }
 
+func (this *MinuteTimeReq) Write(writer *bytes.Buffer) {
+	this.Header.Write(writer)
+	writeUInt16(writer, this.Location)
+	writer.Write([]byte(this.StockCode))
+	writeUInt32(writer, this.Reserved)
+}
+
+func (this *MinuteTimeReq) Size() uint16 {
+	return 14
+}
+
+func NewMinuteTimeReq(seqId uint32, security *entity.Security) *MinuteTimeReq {
+	req := &MinuteTimeReq{
+		Header{
+			Zip: 0xc,
+			SeqId: seqId,
+			PacketType: 0x1,
+			Len: 0,
+			Len1: 0,
+			Cmd: CMD_MINUTE_TIME,
+		},
+		uint16(MarketLocationFromSecurity(security)),
+		security.GetCode(),
+		0,
+	}
+
+	req.Header.Len = req.Size()
+	req.Header.Len1 = req.Header.Len
+
+	return req
+}
+
+func (this *HisMinuteTimeReq) Write(writer *bytes.Buffer) {
+	this.Header.Write(writer)
+	writeUInt32(writer, this.Date)
+	writer.Write([]byte{this.Location})
+	writer.Write([]byte(this.StockCode))
+}
+
+func (this *HisMinuteTimeReq) Size() uint16 {
+	return 13
+}
+
+func NewHisMinuteTimeReq(seqId uint32, date uint32, security *entity.Security) *HisMinuteTimeReq {
+	req := &HisMinuteTimeReq{
+		Header{
+			Zip: 0xc,
+			SeqId: seqId,
+			PacketType: 0x1,
+			Len: 0,
+			Len1: 0,
+			Cmd: CMD_HIS_MINUTE_TIME,
+		},
+		date,
+		MarketLocationFromSecurity(security),
+		security.GetCode(),
+	}
+
+	req.Header.Len = req.Size()
+	req.Header.Len1 = req.Header.Len
+
+	return req
+}
+
 func (this *NamesReq) Write(writer *bytes.Buffer) {
 	this.Header.Write(writer)
 	writeUInt16(writer, this.Block)
@@ -526,7 +719,7 @@ func NewNamesReq(seqId uint32, block uint16, offset uint16) *NamesReq {
 func (this *NamesLenReq) Write(writer *bytes.Buffer) {
 	this.Header.Write(writer)
 	writeUInt16(writer, this.Block)
-	writeUInt32(writer, uint32(date.GetTodayInt()))
+	writeUInt32(writer, this.Date)
 }
 
 func (this *NamesLenReq) Size() uint16 {
@@ -544,6 +737,7 @@ func NewNamesLenReq(seqId uint32, block uint16) *NamesLenReq {
 			Cmd: CMD_NAMES_LEN,
 		},
 		block,
+		uint32(date.GetTodayInt()),
 	}
 
 	req.Header.Len = req.Size()
@@ -616,3 +810,29 @@ func NewGetFileDataReq(seqId uint32, fileName string, offset uint32, length uint
 
 	return req
 }
+
+func (this *HeartBeatReq) Write(writer *bytes.Buffer) {
+	this.Header.Write(writer)
+}
+
+func (this *HeartBeatReq) Size() uint16 {
+	return 2
+}
+
+func NewHeartBeatReq(seqId uint32) *HeartBeatReq {
+	req := &HeartBeatReq{
+		Header{
+			Zip: 0xc,
+			SeqId: seqId,
+			PacketType: 0x1,
+			Len: 0,
+			Len1: 0,
+			Cmd: CMD_HEART_BEAT,
+		},
+	}
+
+	req.Header.Len = req.Size()
+	req.Header.Len1 = req.Header.Len
+
+	return req
+}
@@ -0,0 +1,94 @@
+package network
+
+import (
+	"testing"
+	"reflect"
+	"github.com/stephenlyu/tds/entity"
+	"github.com/stephenlyu/tds/datasource/tdx"
+)
+
+func TestBlockFromCode(t *testing.T) {
+	cases := map[string]int{
+		"600000": BLOCK_SH_A,
+		"900901": BLOCK_SH_B,
+		"999999": BLOCK_INDEX,
+		"000001": BLOCK_SZ_A,
+		"300750": BLOCK_SZ_A,
+		"200002": BLOCK_SZ_B,
+		"399001": BLOCK_INDEX,
+		"": BLOCK_UNKNOWN,
+	}
+
+	for code, block := range cases {
+		if ret := BlockFromCode(code); ret != block {
+			t.Errorf("%s: expected %d, got %d", code, block, ret)
+		}
+	}
+}
+
+func TestBlockFromMarketCode(t *testing.T) {
+	cases := map[string]int{
+		"600000.SH": BLOCK_SH_A,
+		"688981.SH": BLOCK_SH_A,
+		"900901.SH": BLOCK_SH_B,
+		"000001.SH": BLOCK_INDEX,
+		"999999.SH": BLOCK_INDEX,
+		"880001.SH": BLOCK_INDEX,
+		"510050.SH": BLOCK_UNKNOWN,
+		"000001.SZ": BLOCK_SZ_A,
+		"300750.SZ": BLOCK_SZ_A,
+		"200002.SZ": BLOCK_SZ_B,
+		"399001.SZ": BLOCK_INDEX,
+		"159915.SZ": BLOCK_UNKNOWN,
+		"920002.BJ": BLOCK_UNKNOWN,
+		"430047.BJ": BLOCK_UNKNOWN,
+		"899050.BJ": BLOCK_UNKNOWN,
+	}
+
+	for code, block := range cases {
+		security := entity.ParseSecurityUnsafe(code)
+		market := MarketLocationFromSecurity(security)
+		if ret := BlockFromMarketCode(market, security.GetCode()); ret != block {
+			t.Errorf("%s: expected %d, got %d", code, block, ret)
+		}
+		if IsIndexCode(market, security.GetCode()) != (block == BLOCK_INDEX) {
+			t.Errorf("%s: bad IsIndexCode", code)
+		}
+	}
+}
+
+// K线解析按市场和代码判断是否为指数，指数记录多4个字节
+func TestPeriodDataIndexDetection(t *testing.T) {
+	records := []IndexRecord{
+		{Record: entity.Record{Date: tdxdatasource.DayDateToTimestamp(20181102), Open: 10, Close: 10.5, High: 11, Low: 9.5, Volume: 1000, Amount: 10500}, UpCount: 900, DownCount: 300},
+		{Record: entity.Record{Date: tdxdatasource.DayDateToTimestamp(20181105), Open: 10.5, Close: 10, High: 10.5, Low: 9.5, Volume: 2000, Amount: 20000}, UpCount: 200, DownCount: 1000},
+	}
+	plain := []entity.Record{records[0].Record, records[1].Record}
+
+	cases := map[string]bool{
+		"000001.SH": true,
+		"399001.SZ": true,
+		"920002.BJ": false,
+		"000001.SZ": false,
+		"600000.SH": false,
+	}
+
+	for code, index := range cases {
+		var data []byte
+		if index {
+			data = EncodeResp(1, CMD_PERIOD_DATA, EncodeIndexPeriodData(PERIOD_DAY, records), false)
+		} else {
+			data = EncodeResp(1, CMD_PERIOD_DATA, EncodePeriodData(PERIOD_DAY, plain), false)
+		}
+
+		req := NewPeriodDataReq(1, entity.ParseSecurityUnsafe(code), PERIOD_DAY, 0, uint16(len(records)))
+		parser := NewPeriodDataParser(req, data)
+		if parser.Index != index {
+			t.Fatalf("%s: expect index %v", code, index)
+		}
+		err, result := parser.Parse()
+		if err != nil || !reflect.DeepEqual(result, plain) {
+			t.Fatalf("%s: bad records %+v, error: %v", code, result, err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package network
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	CMD_HANDSHAKE1 = 0x000d
+	CMD_HANDSHAKE2 = 0x0fdb
+)
+
+// RawReq is a request whose command is not known yet, Data holds the bytes after Cmd.
+type RawReq struct {
+	Header
+	Data []byte
+}
+
+func (this *RawReq) Write(writer *bytes.Buffer) {
+	this.Header.Write(writer)
+	writer.Write(this.Data)
+}
+
+func (this *RawReq) Size() uint16 {
+	return uint16(2 + len(this.Data))
+}
+
+// ReqParser decodes raw request bytes, as sent by us or the official TDX client, back into request structs.
+type ReqParser struct {
+	RespParser
+	Header Header
+}
+
+func NewReqParser(data []byte) *ReqParser {
+	return &ReqParser{RespParser: RespParser{RawBuffer: data}}
+}
+
+func (this *ReqParser) getStock() *StockDef {
+	return &StockDef{MarketLocation: this.getByte(), StockCode: this.getString(STOCK_CODE_LEN)}
+}
+
+func (this *ReqParser) getStocks(padding int) []*StockDef {
+	count := int(this.getUint16())
+	stocks := []*StockDef{}
+	for i := 0; i < count && this.err == nil; i++ {
+		stocks = append(stocks, this.getStock())
+		this.skipByte(padding)
+	}
+	return stocks
+}
+
+// getFileName reads a zero padded file name
+func (this *ReqParser) getFileName(count int) string {
+	return string(bytes.TrimRight([]byte(this.getString(count)), "\x00"))
+}
+
+func (this *ReqParser) parseHeader() error {
+	if len(this.RawBuffer) < REQ_HEADER_LEN + 2 {
+		return ErrIncompleteData
+	}
+
+	binary.Read(bytes.NewReader(this.RawBuffer), binary.LittleEndian, &this.Header)
+	if this.Header.Zip != 0xc || this.Header.Len != this.Header.Len1 || this.Header.Len < 2 {
+		return ErrBadData
+	}
+	if len(this.RawBuffer) < REQ_HEADER_LEN + int(this.Header.Len) {
+		return ErrIncompleteData
+	}
+
+	this.Data = this.RawBuffer[REQ_HEADER_LEN + 2:REQ_HEADER_LEN + int(this.Header.Len)]
+	this.Current = 0
+	return nil
+}
+
+func (this *ReqParser) Parse() (error, Request) {
+	if err := this.parseHeader(); err != nil {
+		return err, nil
+	}
+
+	command := GetCommand(this.Header.Cmd)
+	if command == nil || command.DecodeReq == nil {
+		return nil, decodeRawReq(this, this.Header)
+	}
+
+	req := command.DecodeReq(this, this.Header)
+	if this.err != nil {
+		return newProtocolError(req, this.err, this.Data), nil
+	}
+	return nil, req
+}
+
+// ParseReq decodes one request from data.
+func ParseReq(data []byte) (error, Request) {
+	return NewReqParser(data).Parse()
+}
+
+// ReadReq reads one request from reader, it's the server side counterpart of ReadResp.
+func ReadReq(reader io.Reader) (error, []byte) {
+	header := make([]byte, REQ_HEADER_LEN)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return err, nil
+	}
+
+	length := binary.LittleEndian.Uint16(header[6:8])
+	if header[0] != 0xc || length != binary.LittleEndian.Uint16(header[8:10]) {
+		return fmt.Errorf("%w: bad request header %x", ErrBadFrame, header), nil
+	}
+
+	data := make([]byte, REQ_HEADER_LEN + int(length))
+	copy(data, header)
+	if _, err := io.ReadFull(reader, data[REQ_HEADER_LEN:]); err != nil {
+		return err, nil
+	}
+	return nil, data
+}
@@ -0,0 +1,112 @@
+package network
+
+import (
+	"testing"
+	"bytes"
+	"reflect"
+	"errors"
+	"encoding/hex"
+	"github.com/stephenlyu/tds/entity"
+)
+
+type writableReq interface {
+	Request
+	Write(writer *bytes.Buffer)
+}
+
+func TestParseReq(t *testing.T) {
+	security := entity.ParseSecurityUnsafe("600000.SH")
+	security1 := entity.ParseSecurityUnsafe("000001.SZ")
+
+	bidReq := NewBidReq(1)
+	bidReq.AddCode(security)
+	bidReq.AddCode(security1)
+	infoExReq := NewInfoExReq(2)
+	infoExReq.AddCode(security1)
+	financeReq := NewFinanceReq(3)
+	financeReq.AddCode(security)
+
+	reqs := []writableReq{
+		bidReq,
+		infoExReq,
+		financeReq,
+		NewInstantTransReq(4, security, 100, 2000),
+		NewHisTransReq(5, 20181102, security1, 0, 2000),
+		NewPeriodDataReq(6, security, PERIOD_DAY, 10, 280),
+		NewMinuteTimeReq(13, security),
+		NewHisMinuteTimeReq(14, 20181102, security1),
+		NewPeriodHisDataReq(7, security1, PERIOD_MINUTE, 20181101, 20181102),
+		NewNamesReq(8, BLOCK_SH_A, 1000),
+		NewNamesLenReq(9, BLOCK_SZ_A),
+		NewHeartBeatReq(10),
+		NewGetFileLenReq(11, "tdxzs.cfg"),
+		NewGetFileDataReq(12, "tdxzs.cfg", 30000, 30000),
+	}
+
+	for _, req := range reqs {
+		buf := new(bytes.Buffer)
+		req.Write(buf)
+
+		err, result := ParseReq(buf.Bytes())
+		if err != nil {
+			t.Fatalf("cmd 0x%04x: %v", req.GetCmd(), err)
+		}
+		if !reflect.DeepEqual(result, req) {
+			t.Errorf("cmd 0x%04x: expected %+v, got %+v", req.GetCmd(), req, result)
+		}
+
+		err, _ = ParseReq(buf.Bytes()[:buf.Len() - 1])
+		if !errors.Is(err, ErrIncompleteData) {
+			t.Errorf("cmd 0x%04x: expected ErrIncompleteData, got %v", req.GetCmd(), err)
+		}
+	}
+}
+
+func TestParseRawReq(t *testing.T) {
+	for _, reqHex := range handshakeReqs {
+		data, _ := hex.DecodeString(reqHex)
+		err, req := ParseReq(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		raw, ok := req.(*RawReq)
+		if !ok {
+			t.Fatalf("expected *RawReq, got %T", req)
+		}
+		if raw.Cmd != CMD_HANDSHAKE1 && raw.Cmd != CMD_HANDSHAKE2 {
+			t.Errorf("unexpected cmd 0x%04x", raw.Cmd)
+		}
+
+		buf := new(bytes.Buffer)
+		raw.Write(buf)
+		if !bytes.Equal(buf.Bytes(), data) {
+			t.Errorf("expected %x, got %x", data, buf.Bytes())
+		}
+	}
+}
+
+func TestReadReq(t *testing.T) {
+	buf := new(bytes.Buffer)
+	NewHeartBeatReq(1).Write(buf)
+	NewNamesReq(2, BLOCK_SZ_A, 0).Write(buf)
+	buf.Write([]byte{0x0c, 0x01})
+
+	for _, seqId := range []uint32{1, 2} {
+		err, data := ReadReq(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err, req := ParseReq(data)
+		if err != nil || req.GetSeqId() != seqId {
+			t.Fatalf("expected seq id %d, got %v %v", seqId, req, err)
+		}
+	}
+
+	if err, _ := ReadReq(buf); err == nil {
+		t.Error("expected error for truncated request")
+	}
+	if err, _ := ReadReq(bytes.NewReader(make([]byte, REQ_HEADER_LEN))); !errors.Is(err, ErrBadFrame) {
+		t.Errorf("expected ErrBadFrame, got %v", err)
+	}
+}
@@ -3,7 +3,6 @@ package network
 import (
 	"encoding/binary"
 	"math"
-	"errors"
 	"compress/zlib"
 	"bytes"
 	"io"
@@ -15,6 +14,7 @@ import (
 	"strings"
 	"github.com/stephenlyu/tds/entity"
 	"github.com/stephenlyu/tds/datasource/tdx"
+	"golang.org/x/text/encoding/simplifiedchinese"
 )
 
 const (
@@ -25,8 +25,13 @@ const (
 const (
 	STOCK_CODE_LEN = 6
 	RESP_HEADER_LEN = 16
+	MAX_RESYNC_LEN = 64 * 1024		// 寻找包头时最多丢弃的字节数
+	SECURITY_RECORD_LEN = 29
+	SECURITY_NAME_LEN = 8
 )
 
+var RESP_MAGIC = []byte{0xb1, 0xcb, 0x74, 0x00}
+
 type Transaction struct {
 	Date uint32
 	Minute uint16
@@ -34,6 +39,7 @@ type Transaction struct {
 	Volume uint32
 	Count uint32
 	BS byte
+	Seq uint16			// 同一分钟内的序号，由GetAllTransactions填写
 }
 
 type InfoExItem struct {
@@ -44,6 +50,103 @@ type InfoExItem struct {
 	RationedShares float32		`json:"rationed_shares"`
 }
 
+// 除权除息事件类别
+const (
+	XDXR_DIVIDEND = 1						// 除权除息
+	XDXR_BONUS_LISTING = 2					// 送配股上市
+	XDXR_NON_TRADABLE_LISTING = 3			// 非流通股上市
+	XDXR_UNKNOWN_CHANGE = 4					// 未知股本变动
+	XDXR_CAPITAL_CHANGE = 5					// 股本变化
+	XDXR_NEW_ISSUE = 6						// 增发新股
+	XDXR_BUYBACK = 7						// 股份回购
+	XDXR_NEW_ISSUE_LISTING = 8				// 增发新股上市
+	XDXR_TRANSFERRED_RATION_LISTING = 9		// 转配股上市
+	XDXR_CONVERTIBLE_BOND_LISTING = 10		// 可转债上市
+	XDXR_SPLIT = 11							// 扩缩股
+	XDXR_NON_TRADABLE_SPLIT = 12			// 非流通股缩股
+	XDXR_CALL_WARRANT = 13					// 送认购权证
+	XDXR_PUT_WARRANT = 14					// 送认沽权证
+)
+
+var xdxrCategoryNames = map[byte]string{
+	XDXR_DIVIDEND: "除权除息",
+	XDXR_BONUS_LISTING: "送配股上市",
+	XDXR_NON_TRADABLE_LISTING: "非流通股上市",
+	XDXR_UNKNOWN_CHANGE: "未知股本变动",
+	XDXR_CAPITAL_CHANGE: "股本变化",
+	XDXR_NEW_ISSUE: "增发新股",
+	XDXR_BUYBACK: "股份回购",
+	XDXR_NEW_ISSUE_LISTING: "增发新股上市",
+	XDXR_TRANSFERRED_RATION_LISTING: "转配股上市",
+	XDXR_CONVERTIBLE_BOND_LISTING: "可转债上市",
+	XDXR_SPLIT: "扩缩股",
+	XDXR_NON_TRADABLE_SPLIT: "非流通股缩股",
+	XDXR_CALL_WARRANT: "送认购权证",
+	XDXR_PUT_WARRANT: "送认沽权证",
+}
+
+// InfoExEvent is one XDXR event of any category, only the fields of its category are set.
+type InfoExEvent struct {
+	Date uint32						`json:"date"`
+	Category byte					`json:"category"`
+
+	// XDXR_DIVIDEND，与InfoExItem相同
+	Bonus float32					`json:"bonus,omitempty"`
+	RationedSharePrice float32		`json:"rationed_share_price,omitempty"`
+	DeliveredShares float32			`json:"delivered_shares,omitempty"`
+	RationedShares float32			`json:"rationed_shares,omitempty"`
+
+	// 股本变动，单位万股
+	FloatSharesBefore float32		`json:"float_shares_before,omitempty"`
+	TotalSharesBefore float32		`json:"total_shares_before,omitempty"`
+	FloatSharesAfter float32		`json:"float_shares_after,omitempty"`
+	TotalSharesAfter float32		`json:"total_shares_after,omitempty"`
+
+	// XDXR_SPLIT, XDXR_NON_TRADABLE_SPLIT
+	SplitRatio float32				`json:"split_ratio,omitempty"`
+
+	// XDXR_CALL_WARRANT, XDXR_PUT_WARRANT
+	StrikePrice float32				`json:"strike_price,omitempty"`
+	WarrantShares float32			`json:"warrant_shares,omitempty"`
+}
+
+func GetXdxrCategoryName(category byte) string {
+	if name, ok := xdxrCategoryNames[category]; ok {
+		return name
+	}
+	return fmt.Sprintf("未知类别%d", category)
+}
+
+func (this *InfoExEvent) IsShareChange() bool {
+	switch this.Category {
+	case XDXR_DIVIDEND, XDXR_SPLIT, XDXR_NON_TRADABLE_SPLIT, XDXR_CALL_WARRANT, XDXR_PUT_WARRANT:
+		return false
+	}
+	return true
+}
+
+func NewDividendEvent(item *InfoExItem) *InfoExEvent {
+	return &InfoExEvent{
+		Date: item.Date,
+		Category: XDXR_DIVIDEND,
+		Bonus: item.Bonus,
+		RationedSharePrice: item.RationedSharePrice,
+		DeliveredShares: item.DeliveredShares,
+		RationedShares: item.RationedShares,
+	}
+}
+
+// ToItem converts a XDXR_DIVIDEND event to the InfoExItem
+func (this *InfoExEvent) ToItem() *InfoExItem {
+	return &InfoExItem{
+		Date: this.Date,
+		Bonus: this.Bonus,
+		DeliveredShares: this.DeliveredShares,
+		RationedSharePrice: this.RationedSharePrice,
+		RationedShares: this.RationedShares,
+	}
+}
+
 type Finance struct {
 	BShares float32				`json:"bShares"`
 	HShares float32				`json:"hShares"`
@@ -87,6 +190,34 @@ func (this *Finance) String() string {
 	return strings.Join(lines, "\n")
 }
 
+// IndexRecord is a K-line record of index with advance/decline counts
+type IndexRecord struct {
+	entity.Record
+	UpCount uint16
+	DownCount uint16
+}
+
+// MinuteTimePoint is one point of the time-share chart
+type MinuteTimePoint struct {
+	Minute uint16			// 从0点开始的分钟数
+	Price uint32			// 与Transaction.Price的单位相同
+	Volume uint32
+	Reserved int
+}
+
+// SecurityInfo is one record of the security list returned by CMD_NAMES
+type SecurityInfo struct {
+	Code string				// 带交易所后缀，如000001.SZ
+	Name string
+	Pinyin string			// 拼音首字母
+	Block int				// BLOCK_XXX
+	VolUnit uint16
+	DecimalPoint byte
+	PreClose float64
+	Reserved1 uint32
+	Reserved2 uint32
+}
+
 type Bid struct {
 	StockCode string
 	Close uint32
@@ -130,6 +261,7 @@ type RespParser struct {
 	RawBuffer []byte
 	Current int
 	Data []byte
+	err error
 }
 
 type InstantTransParser struct {
@@ -168,9 +300,20 @@ type BidParser struct {
 	Total uint16
 }
 
+type MinuteTimeParser struct {
+	RespParser
+	Req Request
+}
+
+type HisMinuteTimeParser struct {
+	RespParser
+	Req Request
+}
+
 type PeriodDataParser struct {
 	RespParser
 	Req Request
+	Index bool				// 指数K线每条记录后面还有上涨家数和下跌家数
 }
 
 type PeriodHisDataParser struct {
@@ -178,6 +321,11 @@ type PeriodHisDataParser struct {
 	Req Request
 }
 
+type HeartBeatParser struct {
+	RespParser
+	Req Request
+}
+
 type GetFileLenParser struct {
 	RespParser
 	Req Request
@@ -188,8 +336,12 @@ type GetFileDataParser struct {
 	Req Request
 }
 
-func (this *RespParser) GetCmd() uint16 {
-	return binary.LittleEndian.Uint16(this.RawBuffer[10:12])
+// GetCmd returns ErrIncompleteData if the response is shorter than its header.
+func (this *RespParser) GetCmd() (error, uint16) {
+	if len(this.RawBuffer) < RESP_HEADER_LEN {
+		return ErrIncompleteData, 0
+	}
+	return nil, binary.LittleEndian.Uint16(this.RawBuffer[10:12])
 }
 
 func (this *RespParser) getHeaderLen() int {
@@ -204,54 +356,110 @@ func (this *RespParser) getLen1() uint16 {
 	return binary.LittleEndian.Uint16(this.RawBuffer[14:16])
 }
 
-func (this *RespParser) GetSeqId() uint32 {
-	return binary.LittleEndian.Uint32(this.RawBuffer[5:9])
+// GetSeqId returns ErrIncompleteData if the response is shorter than its header.
+func (this *RespParser) GetSeqId() (error, uint32) {
+	if len(this.RawBuffer) < RESP_HEADER_LEN {
+		return ErrIncompleteData, 0
+	}
+	return nil, binary.LittleEndian.Uint32(this.RawBuffer[5:9])
+}
+
+// fail records the first decoding error, all later reads return zero values.
+func (this *RespParser) fail(err error) {
+	if this.err == nil {
+		this.err = err
+	}
+}
+
+// Err returns the first error met while decoding.
+func (this *RespParser) Err() error {
+	return this.err
+}
+
+func (this *RespParser) dataError(req Request) error {
+	return newProtocolError(req, this.err, this.Data)
+}
+
+// require checks that count bytes can be read from the current position.
+func (this *RespParser) require(count int) bool {
+	if this.err != nil {
+		return false
+	}
+
+	if this.Current + count < 0 || this.Current + count > len(this.Data) {
+		this.fail(ErrIncompleteData)
+		return false
+	}
+	return true
 }
 
 func (this *RespParser) skipByte(count int) {
-	this.Current += count
+	if this.require(count) {
+		this.Current += count
+	}
 }
 
 func (this *RespParser) skipData(count int) {
-	for count >= 0 {
-		if this.Data[this.Current] < 0x80 {
-			this.skipByte(1)
-		} else if this.Data[this.Current + 1] < 0x80 {
-			this.skipByte(2)
-		} else {
-			this.skipByte(3)
-		}
-
+	for count >= 0 && this.err == nil {
+		this.parseData2()
 		count--
 	}
 }
 
 func (this *RespParser) getByte() byte {
+	if !this.require(1) {
+		return 0
+	}
 	ret := this.Data[this.Current]
 	this.Current++
 	return ret
 }
 
 func (this *RespParser) getUint16() uint16 {
+	if !this.require(2) {
+		return 0
+	}
 	ret := binary.LittleEndian.Uint16(this.Data[this.Current:this.Current + 2])
 	this.Current += 2
 	return ret
 }
 
 func (this *RespParser) getUint32() uint32 {
+	if !this.require(4) {
+		return 0
+	}
 	ret := binary.LittleEndian.Uint32(this.Data[this.Current:this.Current + 4])
 	this.Current += 4
 	return ret
 }
 
 func (this *RespParser) getFloat32() float32 {
-	bits := binary.LittleEndian.Uint32(this.Data[this.Current:this.Current + 4])
-	ret := math.Float32frombits(bits)
-	this.Current += 4
+	return math.Float32frombits(this.getUint32())
+}
+
+func (this *RespParser) getString(count int) string {
+	if !this.require(count) {
+		return ""
+	}
+	ret := string(this.Data[this.Current:this.Current + count])
+	this.Current += count
+	return ret
+}
+
+// getRemain returns all the data not read yet.
+func (this *RespParser) getRemain() []byte {
+	if this.err != nil {
+		return nil
+	}
+	ret := this.Data[this.Current:]
+	this.Current = len(this.Data)
 	return ret
 }
 
 func (this *RespParser) parseData() int {
+	if !this.require(1) {
+		return 0
+	}
 	v := this.Data[this.Current]
 	if v >= 0x40 && v < 0x80 || v >= 0xc0 {
 		return 0x40 - this.parseData2()
@@ -268,13 +476,24 @@ func (this *RespParser) parseData2() int {
 	 //a8 fb b6 01 == 1017 万
 	 //a3 8e 11    == 14.02 万
 	 //82 27         == 2498
-	var v int
 	var nBytes int = 0
-	for this.Data[this.Current + nBytes] >= 0x80 {
+	for {
+		if !this.require(nBytes + 1) {
+			return 0
+		}
+		if this.Data[this.Current + nBytes] < 0x80 {
+			break
+		}
 		nBytes++
+		if nBytes >= 6 {
+			// 最多6个字节
+			this.fail(ErrBadData)
+			return 0
+		}
 	}
 	nBytes++
 
+	var v int
 	switch(nBytes){
 	case 1:
 		v = int(this.Data[this.Current])
@@ -290,55 +509,84 @@ func (this *RespParser) parseData2() int {
 	case 6:
 		// over flow, positive to negative
 		v = ((((int(this.Data[this.Current+5]) * 0x80 + int(this.Data[this.Current+4]) -0x80) * 0x80 +  int(this.Data[this.Current+3]) - 0x80) * 0x80 + int(this.Data[this.Current+2]) - 0x80) * 0x80 + int(this.Data[this.Current+1]) - 0x80) * 0x40 + int(this.Data[this.Current]) - 0x80;
-	default:
-		panic(errors.New("bad data"))
 	}
-	this.skipByte(nBytes)
+	this.Current += nBytes
 	return v
 }
 
 func (this *RespParser) uncompressIf() {
+	this.Current = 0
+
 	if this.getLen() == this.getLen1() {
-		this.Data = this.RawBuffer[this.getHeaderLen():]
-	} else {
-		b := bytes.NewReader(this.RawBuffer[this.getHeaderLen():])
-		var out bytes.Buffer
-		r, _ := zlib.NewReader(b)
-		io.Copy(&out, r)
-		this.Data = out.Bytes()
+		this.Data = this.RawBuffer[this.getHeaderLen():this.getHeaderLen() + int(this.getLen())]
+		return
 	}
 
-	this.Current = 0
+	r, err := zlib.NewReader(bytes.NewReader(this.RawBuffer[this.getHeaderLen():this.getHeaderLen() + int(this.getLen())]))
+	if err != nil {
+		this.fail(&DecompressError{Len: this.getLen(), Len1: this.getLen1(), Err: err})
+		return
+	}
+	defer r.Close()
+
+	// 多读一个字节，用于判断解压后的数据是否比Len1长
+	var out bytes.Buffer
+	_, err = io.Copy(&out, io.LimitReader(r, int64(this.getLen1()) + 1))
+	this.Data = out.Bytes()
+	if err != nil || len(this.Data) != int(this.getLen1()) {
+		this.fail(&DecompressError{Len: this.getLen(), Len1: this.getLen1(), Size: len(this.Data), Err: err})
+	}
+}
+
+// checkResp verifies the response is complete and answers req.
+func (this *RespParser) checkResp(req Request) error {
+	if len(this.RawBuffer) < this.getHeaderLen() || int(this.getLen()) + this.getHeaderLen() > len(this.RawBuffer) {
+		return newProtocolError(req, ErrIncompleteData, this.RawBuffer)
+	}
+
+	if _, seqId := this.GetSeqId(); seqId != req.GetSeqId() {
+		return newProtocolError(req, ErrBadSeqId, this.RawBuffer[:this.getHeaderLen()])
+	}
+
+	if _, cmd := this.GetCmd(); cmd != req.GetCmd() {
+		return newProtocolError(req, ErrBadCmd, this.RawBuffer[:this.getHeaderLen()])
+	}
+
+	return nil
+}
+
+// decode checks the response against req and uncompresses it.
+func (this *RespParser) decode(req Request) error {
+	if err := this.checkResp(req); err != nil {
+		return err
+	}
+
+	this.err = nil
+	this.uncompressIf()
+	if this.err != nil {
+		return this.dataError(req)
+	}
+	return nil
 }
 
 func (this *RespParser) Parse() {
-	if int(this.getLen()) + this.getHeaderLen() > len(this.RawBuffer) {
-		panic(errors.New("incomplete data"))
+	this.err = nil
+	if len(this.RawBuffer) < this.getHeaderLen() || int(this.getLen()) + this.getHeaderLen() > len(this.RawBuffer) {
+		this.fail(ErrIncompleteData)
+		return
 	}
 	this.uncompressIf()
 }
 
 func (this *RespParser) tryParseData() (err error, v int) {
-	err = nil
-	defer func() {
-		if err1 := recover(); err1 != nil {
-			err = err1.(error)
-		}
-	}()
-
 	v = this.parseData()
+	err, this.err = this.err, nil
 	return
 }
 
 func (this *RespParser) tryParseData2() (err error, v int) {
-	err = nil
-	defer func() {
-		if err1 := recover(); err1 != nil {
-			err = err1.(error)
-		}
-	}()
-
 	v = this.parseData2()
+	err, this.err = this.err, nil
 	return
 }
 
@@ -394,27 +642,17 @@ func (this *RespParser) TryParse() {
 }
 
 func (this *InstantTransParser) Parse() (error, []Transaction) {
-	if int(this.getLen()) + this.getHeaderLen() > len(this.RawBuffer) {
-		return errors.New("incomplete data"), nil
+	if err := this.decode(this.Req); err != nil {
+		return err, nil
 	}
 
-	if this.GetSeqId() != this.Req.GetSeqId() {
-		return errors.New("bad seq id"), nil
-	}
-
-	if this.GetCmd() != this.Req.GetCmd() {
-		return errors.New("bad cmd"), nil
-	}
-
-	this.uncompressIf()
-
 	count := this.getUint16()
 	result := make([]Transaction, count)
 
 	first := true
 	var priceBase int
 
-	for i := 0; i < int(count); i++ {
+	for i := 0; i < int(count) && this.err == nil; i++ {
 		trans := &result[i]
 		trans.Minute = this.getUint16()
 		if first {
@@ -430,6 +668,10 @@ func (this *InstantTransParser) Parse() (error, []Transaction) {
 		trans.BS = this.getByte()
 		this.skipByte(1)
 	}
+
+	if this.err != nil {
+		return this.dataError(this.Req), nil
+	}
 	return nil, result
 }
 
@@ -443,20 +685,14 @@ func NewInstantTransParser(req Request, data []byte) *InstantTransParser {
 }
 
 func (this *HisTransParser) Parse() (error, []Transaction) {
-	if int(this.getLen()) + this.getHeaderLen() > len(this.RawBuffer) {
-		return errors.New("incomplete data"), nil
+	req, ok := this.Req.(*HisTransReq)
+	if !ok || req == nil {
+		return &ProtocolError{Err: ErrBadReq}, nil
 	}
-
-	if this.GetSeqId() != this.Req.GetSeqId() {
-		return errors.New("bad seq id"), nil
+	if err := this.decode(this.Req); err != nil {
+		return err, nil
 	}
 
-	if this.GetCmd() != this.Req.GetCmd() {
-		return errors.New("bad cmd"), nil
-	}
-
-	this.uncompressIf()
-
 	count := this.getUint16()
 	this.skipByte(4)
 
@@ -465,9 +701,9 @@ func (this *HisTransParser) Parse() (error, []Transaction) {
 	first := true
 	var priceBase int
 
-	for i := 0; i < int(count); i++ {
+	for i := 0; i < int(count) && this.err == nil; i++ {
 		trans := &result[i]
-		trans.Date = this.Req.(*HisTransReq).Date
+		trans.Date = req.Date
 		trans.Minute = this.getUint16()
 		if first {
 			priceBase = this.parseData2()
@@ -481,6 +717,10 @@ func (this *HisTransParser) Parse() (error, []Transaction) {
 		trans.BS = this.getByte()
 		trans.Count = uint32(this.parseData2())
 	}
+
+	if this.err != nil {
+		return this.dataError(this.Req), nil
+	}
 	return nil, result
 }
 
@@ -493,59 +733,95 @@ func NewHisTransParser(req Request, data []byte) *HisTransParser {
 	}
 }
 
+// Parse returns the XDXR_DIVIDEND events only, use ParseEvents for all the categories.
 func (this *InfoExParser) Parse() (error, map[string][]*InfoExItem) {
-	if int(this.getLen()) + this.getHeaderLen() > len(this.RawBuffer) {
-		return errors.New("incomplete data"), nil
+	err, events := this.ParseEvents()
+	if err != nil {
+		return err, nil
 	}
+	return nil, DividendItems(events)
+}
 
-	if this.GetSeqId() != this.Req.GetSeqId() {
-		return errors.New("bad seq id"), nil
+// DividendItems keeps the XDXR_DIVIDEND events only.
+func DividendItems(events map[string][]*InfoExEvent) map[string][]*InfoExItem {
+	result := map[string][]*InfoExItem{}
+	for stockCode, stockEvents := range events {
+		result[stockCode] = []*InfoExItem{}
+		for _, event := range stockEvents {
+			if event.Category == XDXR_DIVIDEND {
+				result[stockCode] = append(result[stockCode], event.ToItem())
+			}
+		}
 	}
+	return result
+}
 
-	if this.GetCmd() != this.Req.GetCmd() {
-		return errors.New("bad cmd"), nil
+func (this *InfoExParser) parseEvent(event *InfoExEvent) {
+	switch event.Category {
+	case XDXR_DIVIDEND:
+		event.Bonus = this.getFloat32() / 10
+		event.RationedSharePrice = this.getFloat32()
+		event.DeliveredShares = this.getFloat32() / 10
+		event.RationedShares = this.getFloat32() / 10
+	case XDXR_SPLIT, XDXR_NON_TRADABLE_SPLIT:
+		this.skipByte(8)
+		event.SplitRatio = this.getFloat32()
+		this.skipByte(4)
+	case XDXR_CALL_WARRANT, XDXR_PUT_WARRANT:
+		event.StrikePrice = this.getFloat32()
+		this.skipByte(4)
+		event.WarrantShares = this.getFloat32()
+		this.skipByte(4)
+	default:
+		// 股本是float32，与pytdx中get_volume的算法等价
+		event.FloatSharesBefore = this.getFloat32()
+		event.TotalSharesBefore = this.getFloat32()
+		event.FloatSharesAfter = this.getFloat32()
+		event.TotalSharesAfter = this.getFloat32()
 	}
+}
 
-	this.uncompressIf()
+func (this *InfoExParser) ParseEvents() (error, map[string][]*InfoExEvent) {
+	if err := this.decode(this.Req); err != nil {
+		return err, nil
+	}
 
-	result := map[string][]*InfoExItem{}
+	result := map[string][]*InfoExEvent{}
 
 	count := this.getUint16()
 
-	for ; count > 0; count-- {
+	for ; count > 0 && this.err == nil; count-- {
 		loc := this.getByte()
-		stockCode := GetFullCode(loc, string(this.Data[this.Current:this.Current + STOCK_CODE_LEN]))
-		this.skipByte(STOCK_CODE_LEN)
+		stockCode := GetFullCode(loc, this.getString(STOCK_CODE_LEN))
 		recordCount := this.getUint16()
 
-		result[stockCode] = []*InfoExItem{}
+		result[stockCode] = []*InfoExEvent{}
 
-		for ; recordCount > 0; recordCount-- {
+		for ; recordCount > 0 && this.err == nil; recordCount-- {
 			loc := this.getByte()
-			stockCode1 := GetFullCode(loc, string(this.Data[this.Current:this.Current + STOCK_CODE_LEN]))
-			this.skipByte(STOCK_CODE_LEN + 1)
-			if stockCode != stockCode1 {
-				return errors.New(fmt.Sprintf("bad stock code, stockCode: %s stockCode1: %s", stockCode, stockCode1)), nil
+			stockCode1 := GetFullCode(loc, this.getString(STOCK_CODE_LEN))
+			this.skipByte(1)
+			if this.err != nil {
+				break
 			}
-			date := this.getUint32()
-			tp := this.getByte()
-			if tp != 1 {
-				//f1, f2, f3, f4 := this.getFloat32(), this.getFloat32(), this.getFloat32(), this.getFloat32()
-				//fmt.Println("tp:", tp, "date:", date, f1, f2, f3, f4)
-				this.skipByte(16)
-				continue
+			if stockCode != stockCode1 {
+				err := newProtocolError(this.Req, fmt.Errorf("%w, stockCode: %s stockCode1: %s", ErrBadStockCode, stockCode, stockCode1), this.Data)
+				err.Security = stockCode
+				return err, nil
 			}
 
-			obj := &InfoExItem{}
-			obj.Date = date
-			obj.Bonus = this.getFloat32() / 10
-			obj.RationedSharePrice = this.getFloat32()
-			obj.DeliveredShares = this.getFloat32() / 10
-			obj.RationedShares = this.getFloat32() / 10
+			event := &InfoExEvent{}
+			event.Date = this.getUint32()
+			event.Category = this.getByte()
+			this.parseEvent(event)
 
-			result[stockCode] = append(result[stockCode], obj)
+			result[stockCode] = append(result[stockCode], event)
 		}
 	}
+
+	if this.err != nil {
+		return this.dataError(this.Req), nil
+	}
 	return nil, result
 }
 
@@ -568,31 +844,17 @@ func NewFinanceParser(req Request, data []byte) *FinanceParser {
 }
 
 func (this *FinanceParser) Parse() (err error, finances map[string]*Finance) {
-	if int(this.getLen()) + this.getHeaderLen() > len(this.RawBuffer) {
-		err = errors.New("incomplete data")
-		return
-	}
-
-	if this.GetSeqId() != this.Req.GetSeqId() {
-		err = errors.New("bad seq id")
-		return
-	}
-
-	if this.GetCmd() != this.Req.GetCmd() {
-		err = errors.New("bad cmd")
+	if err = this.decode(this.Req); err != nil {
 		return
 	}
 
-	this.uncompressIf()
-
 	finances = make(map[string]*Finance)
 
 	count := this.getUint16()
 
-	for ; count > 0; count-- {
+	for ; count > 0 && this.err == nil; count-- {
 		loc := this.getByte()
-		stockCode := GetFullCode(loc, string(this.Data[this.Current:this.Current + STOCK_CODE_LEN]))
-		this.skipByte(STOCK_CODE_LEN)
+		stockCode := GetFullCode(loc, this.getString(STOCK_CODE_LEN))
 
 		finance := new(Finance)
 
@@ -629,6 +891,9 @@ func (this *FinanceParser) Parse() (err error, finances map[string]*Finance) {
 		finances[stockCode] = finance
 	}
 
+	if this.err != nil {
+		err, finances = this.dataError(this.Req), nil
+	}
 	return
 }
 
@@ -646,44 +911,41 @@ func (this *BidParser) isStockValid(s []byte) bool {
 }
 
 func (this *BidParser) searchStockCode() int {
+	if this.err != nil {
+		return 0
+	}
+
 	for i := this.Current; i < len(this.Data); i++ {
 		if this.isStockValid(this.Data[i:]) {
 			return i - this.Current - 1
 		}
 	}
-	panic(errors.New("no stock code found"))
+	this.fail(ErrNoStockCode)
+	return 0
 }
 
 func (this *BidParser) decrypt() {
+	// 未压缩时Data引用的是RawBuffer，不能原地修改
+	data := make([]byte, len(this.Data))
 	for i, b := range this.Data {
-		this.Data[i] = b ^ 57
+		data[i] = b ^ 57
 	}
+	this.Data = data
 }
 
 func (this *BidParser) Parse() (error, map[string]*Bid) {
-	if int(this.getLen()) + this.getHeaderLen() > len(this.RawBuffer) {
-		return errors.New("incomplete data"), nil
-	}
-
-	if this.GetSeqId() != this.Req.GetSeqId() {
-		return errors.New("bad seq id"), nil
+	if err := this.decode(this.Req); err != nil {
+		return err, nil
 	}
-
-	if this.GetCmd() != this.Req.GetCmd() {
-		return errors.New("bad cmd"), nil
-	}
-
-	this.uncompressIf()
 	this.decrypt()
 
 	result := map[string]*Bid{}
 
 	count := this.getUint16()
 
-	for ; count > 0; count-- {
+	for ; count > 0 && this.err == nil; count-- {
 		loc := this.getByte()
-		stockCode := GetFullCode(loc, string(this.Data[this.Current:this.Current + STOCK_CODE_LEN]))
-		this.skipByte(STOCK_CODE_LEN)
+		stockCode := GetFullCode(loc, this.getString(STOCK_CODE_LEN))
 		this.skipByte(2) // 未知
 
 		bid := &Bid{StockCode: stockCode}
@@ -737,6 +999,10 @@ func (this *BidParser) Parse() (error, map[string]*Bid) {
 			this.skipByte(n)
 		}
 	}
+
+	if this.err != nil {
+		return this.dataError(this.Req), nil
+	}
 	return nil, result
 }
 
@@ -749,41 +1015,151 @@ func NewBidParser(req Request, data []byte) *BidParser {
 	}
 }
 
+// GetTradingMinute returns the minute of the index-th point of the time-share chart, 9:31 ~ 11:30 and 13:01 ~ 15:00.
+func GetTradingMinute(index int) uint16 {
+	if index < 120 {
+		return uint16(9 * 60 + 31 + index)
+	}
+	return uint16(13 * 60 + 1 + index - 120)
+}
+
+// parseMinuteTimePoints parses the points following the count and skip unknown bytes
+func (this *RespParser) parseMinuteTimePoints(skip int) []MinuteTimePoint {
+	count := this.getUint16()
+	this.skipByte(skip)
+
+	result := make([]MinuteTimePoint, count)
+	price := 0
+	for i := 0; i < int(count) && this.err == nil; i++ {
+		point := &result[i]
+		point.Minute = GetTradingMinute(i)
+		price += this.parseData()
+		point.Price = uint32(price)
+		point.Reserved = this.parseData()
+		point.Volume = uint32(this.parseData())
+	}
+	return result
+}
+
+func NewMinuteTimeParser(req Request, data []byte) *MinuteTimeParser {
+	return &MinuteTimeParser{
+		RespParser: RespParser{
+			RawBuffer: data,
+		},
+		Req: req,
+	}
+}
+
+func (this *MinuteTimeParser) Parse() (error, []MinuteTimePoint) {
+	if err := this.decode(this.Req); err != nil {
+		return err, nil
+	}
+
+	result := this.parseMinuteTimePoints(2)
+	if this.err != nil {
+		return this.dataError(this.Req), nil
+	}
+	return nil, result
+}
+
+func NewHisMinuteTimeParser(req Request, data []byte) *HisMinuteTimeParser {
+	return &HisMinuteTimeParser{
+		RespParser: RespParser{
+			RawBuffer: data,
+		},
+		Req: req,
+	}
+}
+
+func (this *HisMinuteTimeParser) Parse() (error, []MinuteTimePoint) {
+	if err := this.decode(this.Req); err != nil {
+		return err, nil
+	}
+
+	result := this.parseMinuteTimePoints(4)
+	if this.err != nil {
+		return this.dataError(this.Req), nil
+	}
+	return nil, result
+}
+
+// NewPeriodDataParser decodes index records if the stock code of req is an index or req.Index is set.
 func NewPeriodDataParser(req Request, data []byte) *PeriodDataParser {
+	index := false
+	if r, ok := req.(*PeriodDataReq); ok {
+		index = r.Index || IsIndexCode(byte(r.Location), r.StockCode)
+	}
+
 	return &PeriodDataParser{
 		RespParser: RespParser{
 			RawBuffer: data,
 		},
 		Req: req,
+		Index: index,
 	}
 }
 
-func (this *PeriodDataParser) Parse() (error, []entity.Record) {
-	if int(this.getLen()) + this.getHeaderLen() > len(this.RawBuffer) {
-		return errors.New("incomplete data"), nil
+// NewIndexPeriodDataParser always decodes index records, e.g. for 000001.SH
+func NewIndexPeriodDataParser(req Request, data []byte) *PeriodDataParser {
+	return &PeriodDataParser{
+		RespParser: RespParser{
+			RawBuffer: data,
+		},
+		Req: req,
+		Index: true,
 	}
+}
 
-	if this.GetSeqId() != this.Req.GetSeqId() {
-		return errors.New("bad seq id"), nil
+// fromTdxDate decodes the date of records, intraday periods have minute in the high 16 bits.
+func fromTdxDate(period uint16, date uint32) uint64 {
+	if !isIntradayPeriod(period) {
+		return tdxdatasource.DayDateToTimestamp(date)
 	}
 
-	if this.GetCmd() != this.Req.GetCmd() {
-		return errors.New("bad cmd"), nil
+	minute := date >> 16
+	v := date & 0xffff
+	year, month, day := v / 2048 + 2004, v % 2048 / 100, v % 2048 % 100
+	return tdxdatasource.DayDateToTimestamp(year * 10000 + month * 100 + day) + uint64(minute) * 60000
+}
+
+func (this *PeriodDataParser) Parse() (error, []entity.Record) {
+	err, indexRecords := this.ParseIndex()
+	if err != nil {
+		return err, nil
 	}
+	return nil, PlainRecords(indexRecords)
+}
 
-	this.uncompressIf()
+// PlainRecords drops the advance/decline counts.
+func PlainRecords(indexRecords []IndexRecord) []entity.Record {
+	result := make([]entity.Record, len(indexRecords))
+	for i := range indexRecords {
+		result[i] = indexRecords[i].Record
+	}
+	return result
+}
+
+// ParseIndex returns zero up and down counts if Index is false.
+func (this *PeriodDataParser) ParseIndex() (error, []IndexRecord) {
+	req, ok := this.Req.(*PeriodDataReq)
+	if !ok || req == nil {
+		return &ProtocolError{Err: ErrBadReq}, nil
+	}
+	if err := this.decode(this.Req); err != nil {
+		return err, nil
+	}
 
 	first := true
 	count := this.getUint16()
 	var priceBase int
 
-	period := periodMap[this.Req.(*PeriodDataReq).Period]
+	period := req.Period
 
-	result := make([]entity.Record, count)
+	result := make([]IndexRecord, count)
 
-	for i := 0; i < int(count); i++ {
+	for i := 0; i < int(count) && this.err == nil; i++ {
 		record := &result[i]
-		record.Date = tdxdatasource.DateToTimestamp(period, this.getUint32())
+		record.Date = fromTdxDate(period, this.getUint32())
 
 		var open int
 		if first {
@@ -795,14 +1171,23 @@ func (this *PeriodDataParser) Parse() (error, []entity.Record) {
 		}
 		record.Open = float64(open) / 1000.0
 
-		priceBase = this.parseData() + int(record.Open)
+		// 收盘价、最高价和最低价都是相对开盘价的差值
+		priceBase = this.parseData() + open
 		record.Close = float64(priceBase) / 1000
-		record.High = float64(this.parseData() + int(record.Open)) / 1000
-		record.Low = float64(this.parseData() + int(record.Open)) / 1000
+		record.High = float64(this.parseData() + open) / 1000
+		record.Low = float64(this.parseData() + open) / 1000
 		record.Volume = float64(this.getFloat32())
 		record.Amount = float64(this.getFloat32())
+
+		if this.Index {
+			record.UpCount = this.getUint16()
+			record.DownCount = this.getUint16()
+		}
 	}
 
+	if this.err != nil {
+		return this.dataError(this.Req), nil
+	}
 	return nil, result
 }
 
@@ -816,21 +1201,15 @@ func NewPeriodHisDataParser(req Request, data []byte) *PeriodHisDataParser {
 }
 
 func (this *PeriodHisDataParser) Parse() (error, []byte) {
-	if int(this.getLen()) + this.getHeaderLen() > len(this.RawBuffer) {
-		return errors.New("incomplete data"), nil
-	}
-
-	if this.GetSeqId() != this.Req.GetSeqId() {
-		return errors.New("bad seq id"), nil
+	if err := this.decode(this.Req); err != nil {
+		return err, nil
 	}
 
-	if this.GetCmd() != this.Req.GetCmd() {
-		return errors.New("bad cmd"), nil
+	this.skipByte(6)
+	if this.err != nil {
+		return this.dataError(this.Req), nil
 	}
-
-	this.uncompressIf()
-
-	return nil, this.Data[6:]
+	return nil, this.getRemain()
 }
 
 func NewGetFileLenParser(req Request, data []byte) *GetFileLenParser {
@@ -843,25 +1222,14 @@ func NewGetFileLenParser(req Request, data []byte) *GetFileLenParser {
 }
 
 func (this *GetFileLenParser) Parse() (err error, length uint32) {
-	if int(this.getLen()) + this.getHeaderLen() > len(this.RawBuffer) {
-		err = errors.New("incomplete data")
-		return
-	}
-
-	if this.GetSeqId() != this.Req.GetSeqId() {
-		err = errors.New("bad seq id")
-		return
-	}
-
-	if this.GetCmd() != this.Req.GetCmd() {
-		err = errors.New("bad cmd")
+	if err = this.decode(this.Req); err != nil {
 		return
 	}
 
-	this.uncompressIf()
-
 	length = this.getUint32()
-
+	if this.err != nil {
+		err = this.dataError(this.Req)
+	}
 	return
 }
 
@@ -875,26 +1243,15 @@ func NewGetFileDataParser(req Request, data []byte) *GetFileDataParser {
 }
 
 func (this *GetFileDataParser) Parse() (err error, length uint32, data []byte) {
-	if int(this.getLen()) + this.getHeaderLen() > len(this.RawBuffer) {
-		err = errors.New("incomplete data")
+	if err = this.decode(this.Req); err != nil {
 		return
 	}
 
-	if this.GetSeqId() != this.Req.GetSeqId() {
-		err = errors.New("bad seq id")
-		return
-	}
-
-	if this.GetCmd() != this.Req.GetCmd() {
-		err = errors.New("bad cmd")
-		return
+	length = this.getUint32()
+	data = this.getRemain()
+	if this.err != nil {
+		err = this.dataError(this.Req)
 	}
-
-	this.uncompressIf()
-
-	length = binary.LittleEndian.Uint32(this.Data[:4])
-	data = this.Data[4:]
-
 	return
 }
 
@@ -908,26 +1265,15 @@ func NewNamesParser(req Request, data []byte) *NamesParser {
 }
 
 func (this *NamesParser) Parse() (err error, length uint16, data []byte) {
-	if int(this.getLen()) + this.getHeaderLen() > len(this.RawBuffer) {
-		err = errors.New("incomplete data")
+	if err = this.decode(this.Req); err != nil {
 		return
 	}
 
-	if this.GetSeqId() != this.Req.GetSeqId() {
-		err = errors.New("bad seq id")
-		return
+	length = this.getUint16()
+	data = this.getRemain()
+	if this.err != nil {
+		err = this.dataError(this.Req)
 	}
-
-	if this.GetCmd() != this.Req.GetCmd() {
-		err = errors.New("bad cmd")
-		return
-	}
-
-	this.uncompressIf()
-
-	length = binary.LittleEndian.Uint16(this.Data[:2])
-	data = this.Data[2:]
-
 	return
 }
 
@@ -941,35 +1287,87 @@ func NewNamesLenParser(req Request, data []byte) *NamesLenParser {
 }
 
 func (this *NamesLenParser) Parse() (err error, length uint32) {
-	if int(this.getLen()) + this.getHeaderLen() > len(this.RawBuffer) {
-		err = errors.New("incomplete data")
+	if err = this.decode(this.Req); err != nil {
 		return
 	}
 
-	if this.GetSeqId() != this.Req.GetSeqId() {
-		err = errors.New("bad seq id")
-		return
+	length = uint32(this.getUint16())
+	if this.err != nil {
+		err = this.dataError(this.Req)
 	}
+	return
+}
 
-	if this.GetCmd() != this.Req.GetCmd() {
-		err = errors.New("bad cmd")
-		return
+// ParseSecurityList decodes the security records of market returned by GetNamesData.
+func ParseSecurityList(market uint16, data []byte) (error, []*SecurityInfo) {
+	if len(data) % SECURITY_RECORD_LEN != 0 {
+		return ErrIncompleteData, nil
+	}
+
+	decoder := simplifiedchinese.GBK.NewDecoder()
+	parser := &RespParser{Data: data}
+	result := make([]*SecurityInfo, 0, len(data) / SECURITY_RECORD_LEN)
+	for parser.Current < len(data) && parser.err == nil {
+		info := &SecurityInfo{}
+		code := parser.getString(STOCK_CODE_LEN)
+		info.Code = GetFullCode(byte(market), code)
+		info.VolUnit = parser.getUint16()
+		gbkName := bytes.TrimRight([]byte(parser.getString(SECURITY_NAME_LEN)), "\x00 ")
+		name, err := decoder.Bytes(gbkName)
+		if err != nil {
+			parser.fail(ErrBadData)
+		}
+		info.Name = string(name)
+		info.Pinyin = GetPinyinInitials(gbkName)
+		info.Reserved1 = parser.getUint32()
+		info.DecimalPoint = parser.getByte()
+		// 昨收是float32，与pytdx中get_volume的算法等价
+		info.PreClose = float64(parser.getFloat32())
+		info.Reserved2 = parser.getUint32()
+		if parser.err == nil {
+			info.Block = BlockFromMarketCode(byte(market), code)
+		}
+		result = append(result, info)
 	}
 
-	this.uncompressIf()
+	if parser.err != nil {
+		return parser.err, nil
+	}
+	return nil, result
+}
 
-	length = uint32(this.getUint16())
+func NewHeartBeatParser(req Request, data []byte) *HeartBeatParser {
+	return &HeartBeatParser{
+		RespParser: RespParser{
+			RawBuffer: data,
+		},
+		Req: req,
+	}
+}
 
-	return
+func (this *HeartBeatParser) Parse() error {
+	return this.checkResp(this.Req)
 }
 
 func NewRespParser(data []byte) *RespParser {
 	return &RespParser{RawBuffer: data}
 }
 
+// isMagicAt checks whether the bytes from i could be the beginning of the magic number,
+// the tail of the buffer may contain only part of it.
+func isMagicAt(buffer []byte, i int) bool {
+	for k := 0; k < len(RESP_MAGIC) && i + k < len(buffer); k++ {
+		if buffer[i + k] != RESP_MAGIC[k] {
+			return false
+		}
+	}
+	return true
+}
+
 func ReadResp(conn net.Conn) (error, []byte) {
 	header := make([]byte, RESP_HEADER_LEN)
 	nRead := 0
+	nSkipped := 0
 	for nRead < RESP_HEADER_LEN {
 		n, err := conn.Read(header[nRead:])
 		if err != nil {
@@ -982,18 +1380,32 @@ func ReadResp(conn net.Conn) (error, []byte) {
 
 		// Find magic number
 		var i int
-		for i < nRead - 4 {
-			if header[i] != 0xb1 && header[i+1] != 0xeb && header[i+2] != 0x74 && header[i+3] != 00 {
-				i++
-			} else {
-				break
+		for i < nRead && !isMagicAt(header[:nRead], i) {
+			i++
+		}
+		if i > 0 {
+			nSkipped += i
+			if nSkipped > MAX_RESYNC_LEN {
+				log.Errorf("ReadResp - magic number not found, %d bytes skipped", nSkipped)
+				return ErrBadFrame, nil
 			}
+			copy(header[0:nRead-i], header[i:nRead])
+			nRead -= i
 		}
-		copy(header[0:nRead-i], header[i:nRead])
-		nRead -= i
+	}
+
+	if nSkipped > 0 {
+		log.Warnf("ReadResp - resync, %d bytes skipped", nSkipped)
 	}
 
 	length := int(binary.LittleEndian.Uint16(header[12:14]))
+	length1 := int(binary.LittleEndian.Uint16(header[14:16]))
+	// Len和Len1都是uint16, 不会超过64K, 解压时按Len1限制输出
+	if length != length1 && (length == 0 || length1 == 0) {
+		log.Errorf("ReadResp - bad length, len: %d len1: %d", length, length1)
+		return ErrBadFrame, nil
+	}
+
 	result := make([]byte, length + RESP_HEADER_LEN)
 	copy(result[:RESP_HEADER_LEN], header[:])
 
@@ -0,0 +1,189 @@
+package network
+
+import (
+	"testing"
+	"net"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"encoding/hex"
+	"reflect"
+	"github.com/stephenlyu/tds/entity"
+	"github.com/stephenlyu/tds/datasource/tdx"
+)
+
+func writeAsync(conn net.Conn, chunks ...[]byte) {
+	go func() {
+		for _, chunk := range chunks {
+			conn.Write(chunk)
+		}
+	}()
+}
+
+func TestReadRespResync(t *testing.T) {
+	frame := buildResp(7, CMD_HEART_BEAT, []byte{1, 2, 3})
+
+	cases := [][][]byte{
+		{frame},
+		{[]byte{0x00, 0xb1, 0xcb, 0x11}, frame},
+		{[]byte{0xb1, 0xb1, 0xcb, 0x74}, frame},
+		{[]byte{0x01, 0x02}, frame[:2], frame[2:5], frame[5:]},
+		{bytes.Repeat([]byte{0xb1, 0xcb, 0x74}, 10), frame},
+	}
+
+	for i, chunks := range cases {
+		client, server := net.Pipe()
+		writeAsync(server, chunks...)
+
+		err, data := ReadResp(client)
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		if !bytes.Equal(data, frame) {
+			t.Fatalf("case %d: expect %x, got %x", i, frame, data)
+		}
+		client.Close()
+		server.Close()
+	}
+}
+
+func TestReadRespNoMagic(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	writeAsync(server, make([]byte, MAX_RESYNC_LEN + RESP_HEADER_LEN * 2))
+
+	err, _ := ReadResp(client)
+	if !errors.Is(err, ErrBadFrame) || !IsRetryable(err) {
+		t.Fatalf("expect ErrBadFrame, got %v", err)
+	}
+}
+
+func TestReadRespBadLength(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	frame := buildResp(7, CMD_HEART_BEAT, nil)
+	binary.LittleEndian.PutUint16(frame[14:16], 100)
+	writeAsync(server, frame)
+
+	err, _ := ReadResp(client)
+	if !errors.Is(err, ErrBadFrame) {
+		t.Fatalf("expect ErrBadFrame, got %v", err)
+	}
+}
+
+func TestReadRespMaxLength(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	frame := buildResp(7, CMD_HEART_BEAT, make([]byte, 0xffff))
+	writeAsync(server, frame)
+
+	err, data := ReadResp(client)
+	if err != nil || !bytes.Equal(data, frame) {
+		t.Fatalf("expect the whole frame, error: %v", err)
+	}
+}
+
+func buildZipResp(seqId uint32, cmd uint16, body []byte, len1 int) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write(body)
+	w.Close()
+
+	data := buildResp(seqId, cmd, buf.Bytes())
+	binary.LittleEndian.PutUint16(data[14:16], uint16(len1))
+	return data
+}
+
+func TestUncompress(t *testing.T) {
+	req := NewNamesLenReq(3, 0)
+	body := []byte{0x34, 0x12, 0, 0, 0, 0, 0, 0, 0, 0}
+
+	err, length := NewNamesLenParser(req, buildZipResp(3, CMD_NAMES_LEN, body, len(body))).Parse()
+	if err != nil || length != 0x1234 {
+		t.Fatalf("bad result, err: %v length: %x", err, length)
+	}
+
+	// 数据不是zlib格式
+	notZipped := buildResp(3, CMD_NAMES_LEN, body)
+	binary.LittleEndian.PutUint16(notZipped[14:16], 100)
+
+	for _, data := range [][]byte{
+		buildZipResp(3, CMD_NAMES_LEN, body, len(body) + 1),
+		buildZipResp(3, CMD_NAMES_LEN, body, len(body) - 1),
+		notZipped,
+	} {
+		err, _ := NewNamesLenParser(req, data).Parse()
+
+		var decompressErr *DecompressError
+		if !errors.As(err, &decompressErr) || !errors.Is(err, ErrDecompress) {
+			t.Fatalf("expect DecompressError, got %v", err)
+		}
+		if IsRetryable(err) {
+			t.Fatalf("decompress error should not be retryable")
+		}
+	}
+}
+
+// 600000.SH三条日线，按协议格式逐字节构造，不经过EncodePeriodData：
+// 首条开盘价为无符号数，之后的开盘价相对上一条收盘价，收盘价、最高价和最低价相对本条开盘价，单位为厘
+const periodDataRespHex = "b1cb74000c01000000002d053900390003006ef03301a69e0132a4015e0020f147f902954e71f033010ac20214e00200007048f902154f72f03301001e9001da0100007047f902154e"
+
+func TestPeriodDataGolden(t *testing.T) {
+	data, _ := hex.DecodeString(periodDataRespHex)
+	req := NewPeriodDataReq(1, entity.ParseSecurityUnsafe("600000.SH"), PERIOD_DAY, 0, 3)
+	err, result := NewPeriodDataParser(req, data).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []entity.Record{
+		{Date: tdxdatasource.DayDateToTimestamp(20181102), Open: 10.15, Close: 10.2, High: 10.25, Low: 10.12, Volume: 123456, Amount: 1.25e9},
+		{Date: tdxdatasource.DayDateToTimestamp(20181105), Open: 10.21, Close: 10.08, High: 10.23, Low: 10.05, Volume: 245760, Amount: 2.5e9},
+		{Date: tdxdatasource.DayDateToTimestamp(20181106), Open: 10.08, Close: 10.11, High: 10.16, Low: 9.99, Volume: 61440, Amount: 6.25e8},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, result)
+	}
+}
+
+func TestRespHeaderShort(t *testing.T) {
+	parser := NewRespParser(buildResp(7, CMD_HIS_TRANS, nil)[:RESP_HEADER_LEN - 1])
+	if err, _ := parser.GetCmd(); err != ErrIncompleteData {
+		t.Fatalf("expect ErrIncompleteData, got %v", err)
+	}
+	if err, _ := parser.GetSeqId(); err != ErrIncompleteData {
+		t.Fatalf("expect ErrIncompleteData, got %v", err)
+	}
+
+	parser = NewRespParser(buildResp(7, CMD_HIS_TRANS, nil))
+	err1, cmd := parser.GetCmd()
+	err2, seqId := parser.GetSeqId()
+	if err1 != nil || err2 != nil || cmd != CMD_HIS_TRANS || seqId != 7 {
+		t.Fatalf("bad header, cmd: %d seq id: %d", cmd, seqId)
+	}
+}
+
+// 请求的类型和解析器不匹配时返回错误，不能panic
+func TestParseBadReq(t *testing.T) {
+	data := buildResp(7, CMD_HIS_TRANS, EncodeHisTransData([]Transaction{{Minute: 570, Price: 1000, Volume: 1}}))
+	header := Header{SeqId: 7, Cmd: CMD_HIS_TRANS}
+
+	err, _ := NewHisTransParser(&PeriodDataReq{Header: header}, data).Parse()
+	var protocolErr *ProtocolError
+	if !errors.As(err, &protocolErr) || !errors.Is(err, ErrBadReq) {
+		t.Fatalf("expect ProtocolError, got %v", err)
+	}
+	if err, _ := NewIndexPeriodDataParser(header, data).ParseIndex(); !errors.Is(err, ErrBadReq) {
+		t.Fatalf("expect ErrBadReq, got %v", err)
+	}
+	if err, _ := NewPeriodDataParser(nil, data).Parse(); !errors.Is(err, ErrBadReq) {
+		t.Fatalf("expect ErrBadReq, got %v", err)
+	}
+}
@@ -0,0 +1,289 @@
+package network
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"github.com/stephenlyu/tds/entity"
+)
+
+const SNAPSHOT_CHUNK_SIZE = 20			// 每个行情请求的证券数
+
+// BidDelta is the change of one code between two snapshots.
+type BidDelta struct {
+	StockCode string
+	Fields []string				// 变化的字段名，新出现的代码包括所有字段
+	Old *Bid					// 新出现的代码为nil
+	New *Bid
+}
+
+// SnapshotUpdate is sent to the subscribers after every refresh which changes something.
+type SnapshotUpdate struct {
+	Time time.Time
+	Deltas []*BidDelta
+}
+
+// Snapshot is a consistent view of the latest bids, the bids must not be modified.
+type Snapshot struct {
+	Time time.Time						// 最近一次刷新的时间
+	Bids map[string]*Bid
+	Updated map[string]time.Time		// 每个代码最近一次请求成功的时间，请求失败的代码保留旧的时间
+}
+
+// IsStale reports whether the code failed in the latest refresh, its bid may be out of date.
+func (this *Snapshot) IsStale(stockCode string) bool {
+	return this.Updated[stockCode].Before(this.Time)
+}
+
+// SnapshotEngine polls the bids of the securities concurrently and keeps the latest snapshot.
+type SnapshotEngine struct {
+	api *BizApi
+	securities []*entity.Security
+	concurrency int
+
+	refreshLock sync.Mutex
+	lock sync.RWMutex
+	snapshot *Snapshot
+
+	subLock sync.Mutex
+	subscribers map[chan *SnapshotUpdate]bool
+}
+
+// NewSnapshotEngine uses as many concurrent requests as the pool capacity by default.
+func NewSnapshotEngine(api *BizApi, securities []*entity.Security) *SnapshotEngine {
+	concurrency := api.api.options.MaxCap
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &SnapshotEngine{
+		api: api,
+		securities: securities,
+		concurrency: concurrency,
+		snapshot: &Snapshot{Bids: map[string]*Bid{}},
+		subscribers: map[chan *SnapshotUpdate]bool{},
+	}
+}
+
+// SetConcurrency waits for the running refresh, the new concurrency is used from the next refresh.
+func (this *SnapshotEngine) SetConcurrency(concurrency int) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	this.refreshLock.Lock()
+	defer this.refreshLock.Unlock()
+	this.concurrency = concurrency
+}
+
+// Snapshot returns the latest snapshot, it is never modified after returned.
+func (this *SnapshotEngine) Snapshot() *Snapshot {
+	this.lock.RLock()
+	defer this.lock.RUnlock()
+	return this.snapshot
+}
+
+func (this *SnapshotEngine) GetBid(stockCode string) *Bid {
+	return this.Snapshot().Bids[stockCode]
+}
+
+// Subscribe returns a channel receiving the updates. Updates are dropped if the channel is full,
+// slow subscribers should resync with Snapshot.
+func (this *SnapshotEngine) Subscribe(bufferSize int) <-chan *SnapshotUpdate {
+	ch := make(chan *SnapshotUpdate, bufferSize)
+	this.subLock.Lock()
+	defer this.subLock.Unlock()
+	this.subscribers[ch] = true
+	return ch
+}
+
+// Unsubscribe closes the channel returned by Subscribe.
+func (this *SnapshotEngine) Unsubscribe(ch <-chan *SnapshotUpdate) {
+	this.subLock.Lock()
+	defer this.subLock.Unlock()
+	for c := range this.subscribers {
+		if c == ch {
+			delete(this.subscribers, c)
+			close(c)
+			return
+		}
+	}
+}
+
+func (this *SnapshotEngine) publish(update *SnapshotUpdate) {
+	this.subLock.Lock()
+	defer this.subLock.Unlock()
+	for ch := range this.subscribers {
+		select {
+		case ch <- update:
+		default:
+		}
+	}
+}
+
+// fetch requests the chunks concurrently, failed chunks are skipped and the first error is returned.
+// fetched contains the codes of the succeeded chunks.
+func (this *SnapshotEngine) fetch(ctx context.Context) (err error, bids map[string]*Bid, fetched []string) {
+	chunks := make(chan []*entity.Security)
+	go func() {
+		defer close(chunks)
+		for i := 0; i < len(this.securities); i += SNAPSHOT_CHUNK_SIZE {
+			end := i + SNAPSHOT_CHUNK_SIZE
+			if end > len(this.securities) {
+				end = len(this.securities)
+			}
+			select {
+			case chunks <- this.securities[i:end]:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var lock sync.Mutex
+	var firstErr error
+	result := map[string]*Bid{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < this.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range chunks {
+				err, bids := this.api.api.GetBidContext(ctx, chunk)
+				lock.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+				} else {
+					for k, v := range bids {
+						result[k] = v
+					}
+					for _, security := range chunk {
+						fetched = append(fetched, security.String())
+					}
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+	return firstErr, result, fetched
+}
+
+// Refresh polls all the securities once. The codes of failed chunks keep their previous bids and
+// update times, so the snapshot is updated even if an error is returned.
+func (this *SnapshotEngine) Refresh(ctx context.Context) error {
+	this.refreshLock.Lock()
+	defer this.refreshLock.Unlock()
+
+	err, bids, fetched := this.fetch(ctx)
+
+	old := this.Snapshot()
+	now := time.Now()
+	snapshot := &Snapshot{Time: now, Bids: make(map[string]*Bid, len(old.Bids)), Updated: make(map[string]time.Time, len(old.Updated))}
+	for k, v := range old.Bids {
+		snapshot.Bids[k] = v
+	}
+	for k, v := range old.Updated {
+		snapshot.Updated[k] = v
+	}
+	for _, code := range fetched {
+		snapshot.Updated[code] = now
+	}
+
+	deltas := []*BidDelta{}
+	for code, bid := range bids {
+		oldBid := old.Bids[code]
+		if fields := DiffBid(oldBid, bid); len(fields) > 0 {
+			deltas = append(deltas, &BidDelta{StockCode: code, Fields: fields, Old: oldBid, New: bid})
+		}
+		snapshot.Bids[code] = bid
+	}
+	sort.Slice(deltas, func(i, j int) bool {
+		return deltas[i].StockCode < deltas[j].StockCode
+	})
+
+	this.lock.Lock()
+	this.snapshot = snapshot
+	this.lock.Unlock()
+
+	if len(deltas) > 0 {
+		this.publish(&SnapshotUpdate{Time: snapshot.Time, Deltas: deltas})
+	}
+	return err
+}
+
+// Run refreshes every interval until ctx is done, refresh errors are passed to onError if not nil.
+func (this *SnapshotEngine) Run(ctx context.Context, interval time.Duration, onError func(err error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := this.Refresh(ctx); err != nil && ctx.Err() == nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// DiffBid returns the names of the changed fields, all fields if old is nil.
+func DiffBid(old, new *Bid) []string {
+	fields := []string{}
+	newValue := reflect.ValueOf(new).Elem()
+	t := newValue.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if old == nil || reflect.ValueOf(old).Elem().Field(i).Interface() != newValue.Field(i).Interface() {
+			fields = append(fields, t.Field(i).Name)
+		}
+	}
+	return fields
+}
+
+// 北交所的股票和指数，BlockFromMarketCode不区分北交所的代码
+var bjSnapshotPrefixes = []string{"43", "83", "87", "92", "899"}
+
+func isBJSnapshotCode(code string) bool {
+	for _, prefix := range bjSnapshotPrefixes {
+		if strings.HasPrefix(code, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *BizApi) GetSnapshotSecurities() (error, []*entity.Security) {
+	return this.GetSnapshotSecuritiesContext(context.Background())
+}
+
+// GetSnapshotSecuritiesContext returns all the A shares and indexes of SZ, SH and BJ.
+func (this *BizApi) GetSnapshotSecuritiesContext(ctx context.Context) (error, []*entity.Security) {
+	err, infos := this.GetAllSecurityListContext(ctx)
+	if err != nil {
+		return err, nil
+	}
+
+	result := []*entity.Security{}
+	for _, info := range infos {
+		code := info.Code
+		switch {
+		case strings.HasSuffix(code, ".BJ") && isBJSnapshotCode(code):
+		case info.Block == BLOCK_SH_A, info.Block == BLOCK_SZ_A, info.Block == BLOCK_INDEX:
+		default:
+			continue
+		}
+		result = append(result, entity.ParseSecurityUnsafe(code))
+	}
+	return nil, result
+}
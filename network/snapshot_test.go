@@ -0,0 +1,37 @@
+package network
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffBid(t *testing.T) {
+	old := &Bid{StockCode: "000001.SZ", Close: 1050, Vol: 100, BuyPrice1: 1049}
+
+	fields := DiffBid(nil, old)
+	if len(fields) != reflect.TypeOf(Bid{}).NumField() || fields[0] != "StockCode" {
+		t.Fatalf("all fields expected for a new code: %v", fields)
+	}
+
+	same := *old
+	if fields := DiffBid(old, &same); len(fields) != 0 {
+		t.Fatalf("expect no change, got %v", fields)
+	}
+
+	changed := *old
+	changed.Close = 1051
+	changed.Vol = 101
+	changed.SellVol5 = 3
+	if fields := DiffBid(old, &changed); !reflect.DeepEqual(fields, []string{"Close", "Vol", "SellVol5"}) {
+		t.Fatalf("bad fields: %v", fields)
+	}
+}
+
+func TestSnapshotIsStale(t *testing.T) {
+	now := time.Now()
+	snapshot := &Snapshot{Time: now, Updated: map[string]time.Time{"000001.SZ": now, "000002.SZ": now.Add(-time.Second)}}
+	if snapshot.IsStale("000001.SZ") || !snapshot.IsStale("000002.SZ") || !snapshot.IsStale("000003.SZ") {
+		t.Fatal("bad stale codes")
+	}
+}
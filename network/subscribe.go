@@ -0,0 +1,260 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"github.com/stephenlyu/tds/entity"
+)
+
+// 订阅类型，可以组合
+const (
+	SUBSCRIBE_BID = 1
+	SUBSCRIBE_TRANS = 2
+)
+
+type SubscribeOptions struct {
+	Interval time.Duration			// 轮询间隔
+	BufferSize int					// 通道缓冲，缓冲满时轮询等待消费者
+	TransPageSize uint16			// 每次请求的成交数
+	MaxTransPages int				// 每次轮询每个代码最多请求的页数
+	OnError func(err error)			// 轮询出错时调用，出错的代码在下次轮询时重试
+}
+
+func DefaultSubscribeOptions() *SubscribeOptions {
+	return &SubscribeOptions{
+		Interval: 3 * time.Second,
+		BufferSize: 100,
+		TransPageSize: 1000,
+		MaxTransPages: 10,
+	}
+}
+
+// QuoteUpdate is the change of one security in one poll.
+type QuoteUpdate struct {
+	StockCode string
+	Bid *Bid						// 行情没有变化时为nil
+	Transactions []Transaction		// 新成交，按时间排序
+	TransTruncated bool				// 新成交超过MaxTransPages页，较早的新成交没有取到
+}
+
+// 相同分钟内相同价格、成交量和方向的成交可能有多笔，按出现次数去重
+type transKey struct {
+	Minute uint16
+	Price uint32
+	Volume uint32
+	BS byte
+}
+
+type transTracker struct {
+	started bool
+	lastMinute uint16
+	seen map[transKey]int			// lastMinute的成交
+}
+
+func newTransKey(t *Transaction) transKey {
+	return transKey{Minute: t.Minute, Price: t.Price, Volume: t.Volume, BS: t.BS}
+}
+
+// update returns the new transactions in window, window must cover all the transactions of lastMinute.
+func (this *transTracker) update(window []Transaction) []Transaction {
+	if len(window) == 0 {
+		return nil
+	}
+	if this.started && window[len(window) - 1].Minute < this.lastMinute {
+		// 新的交易日
+		this.started = false
+	}
+
+	result := []Transaction{}
+	counts := map[transKey]int{}
+	for _, t := range window {
+		if this.started && t.Minute < this.lastMinute {
+			continue
+		}
+		key := newTransKey(&t)
+		counts[key]++
+		if !this.started || counts[key] > this.seen[key] {
+			result = append(result, t)
+		}
+	}
+
+	this.started = true
+	this.lastMinute = window[len(window) - 1].Minute
+	this.seen = map[transKey]int{}
+	for _, t := range window {
+		if t.Minute == this.lastMinute {
+			this.seen[newTransKey(&t)]++
+		}
+	}
+	return result
+}
+
+// Subscription polls the bids and transactions and sends the changes to C, C is closed after Close.
+type Subscription struct {
+	C <-chan *QuoteUpdate
+
+	api *BizApi
+	securities []*entity.Security
+	kinds int
+	options SubscribeOptions
+
+	ch chan *QuoteUpdate
+	cancel context.CancelFunc
+	done chan struct{}
+
+	bids map[string]*Bid
+	trans map[string]*transTracker
+}
+
+func (this *BizApi) Subscribe(securities []*entity.Security, kinds int) *Subscription {
+	return this.SubscribeWithOptions(context.Background(), securities, kinds, DefaultSubscribeOptions())
+}
+
+// SubscribeWithOptions starts polling until ctx is done or Close is called.
+// The first update of each security contains the current bid and the latest page of transactions.
+func (this *BizApi) SubscribeWithOptions(ctx context.Context, securities []*entity.Security, kinds int, options *SubscribeOptions) *Subscription {
+	opts := *options
+	defaults := DefaultSubscribeOptions()
+	if opts.Interval <= 0 {
+		opts.Interval = defaults.Interval
+	}
+	if opts.TransPageSize == 0 {
+		opts.TransPageSize = defaults.TransPageSize
+	}
+	if opts.MaxTransPages <= 0 {
+		opts.MaxTransPages = defaults.MaxTransPages
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	ch := make(chan *QuoteUpdate, opts.BufferSize)
+	result := &Subscription{
+		C: ch,
+		api: this,
+		securities: securities,
+		kinds: kinds,
+		options: opts,
+		ch: ch,
+		cancel: cancel,
+		done: make(chan struct{}),
+		bids: map[string]*Bid{},
+		trans: map[string]*transTracker{},
+	}
+	go result.run(ctx)
+	return result
+}
+
+// Close stops polling and waits until C is closed, pending updates in C are still readable.
+func (this *Subscription) Close() {
+	this.cancel()
+	<-this.done
+}
+
+func (this *Subscription) run(ctx context.Context) {
+	defer close(this.done)
+	defer close(this.ch)
+
+	ticker := time.NewTicker(this.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		if !this.poll(ctx) {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (this *Subscription) onError(ctx context.Context, err error) {
+	if ctx.Err() == nil && this.options.OnError != nil {
+		this.options.OnError(err)
+	}
+}
+
+// poll returns false if ctx is done.
+func (this *Subscription) poll(ctx context.Context) bool {
+	updates := map[string]*QuoteUpdate{}
+	getUpdate := func(code string) *QuoteUpdate {
+		update, ok := updates[code]
+		if !ok {
+			update = &QuoteUpdate{StockCode: code}
+			updates[code] = update
+		}
+		return update
+	}
+
+	if this.kinds & SUBSCRIBE_BID != 0 {
+		err, bids := this.api.GetBidContext(ctx, this.securities)
+		if err != nil {
+			this.onError(ctx, err)
+		}
+		for code, bid := range bids {
+			if len(DiffBid(this.bids[code], bid)) > 0 {
+				getUpdate(code).Bid = bid
+				this.bids[code] = bid
+			}
+		}
+	}
+
+	if this.kinds & SUBSCRIBE_TRANS != 0 {
+		for _, security := range this.securities {
+			err, transactions, truncated := this.fetchTransactions(ctx, security)
+			if err != nil {
+				this.onError(ctx, err)
+				continue
+			}
+			if truncated {
+				this.onError(ctx, fmt.Errorf("%w, security: %s", ErrTransTruncated, security.String()))
+				getUpdate(security.String()).TransTruncated = true
+			}
+			if len(transactions) > 0 {
+				getUpdate(security.String()).Transactions = transactions
+			}
+		}
+	}
+
+	for _, security := range this.securities {
+		update, ok := updates[security.String()]
+		if !ok {
+			continue
+		}
+		select {
+		case this.ch <- update:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return ctx.Err() == nil
+}
+
+// fetchTransactions pages backward until all the transactions of the last seen minute are fetched.
+// New transactions during paging shift the offsets, the pager removes the overlap.
+// truncated is true if the last seen minute is not reached in MaxTransPages pages.
+func (this *Subscription) fetchTransactions(ctx context.Context, security *entity.Security) (err error, transactions []Transaction, truncated bool) {
+	code := security.String()
+	tracker, ok := this.trans[code]
+	if !ok {
+		tracker = &transTracker{}
+		this.trans[code] = tracker
+	}
+
+	pager := newInstantTransPager(this.api.api, security, this.options.TransPageSize)
+	truncated = true
+	for page := 0; page < this.options.MaxTransPages; page++ {
+		if err = pager.next(ctx); err != nil {
+			return err, nil, false
+		}
+
+		window := pager.result
+		if !pager.more || !tracker.started || window[0].Minute < tracker.lastMinute {
+			truncated = false
+			break
+		}
+	}
+	return nil, tracker.update(pager.result), truncated
+}
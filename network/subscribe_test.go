@@ -0,0 +1,35 @@
+package network
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTransTracker(t *testing.T) {
+	t1 := Transaction{Minute: 570, Price: 1050, Volume: 10, BS: BS_BUY}
+	t2 := Transaction{Minute: 571, Price: 1049, Volume: 20, BS: BS_SELL}
+	t3 := Transaction{Minute: 571, Price: 1050, Volume: 10, BS: BS_BUY}
+	t4 := Transaction{Minute: 572, Price: 1051, Volume: 5, BS: BS_BUY}
+
+	tracker := &transTracker{}
+	cases := []struct {
+		window []Transaction
+		expect []Transaction
+	}{
+		{[]Transaction{t1, t2}, []Transaction{t1, t2}},
+		{[]Transaction{t1, t2}, []Transaction{}},
+		// 同一分钟内重复的成交
+		{[]Transaction{t1, t2, t2, t3}, []Transaction{t2, t3}},
+		{[]Transaction{t2, t2, t3, t3, t4}, []Transaction{t3, t4}},
+		{[]Transaction{t4}, []Transaction{}},
+		{nil, nil},
+		// 新的交易日
+		{[]Transaction{t1}, []Transaction{t1}},
+	}
+
+	for i, c := range cases {
+		if result := tracker.update(c.window); !reflect.DeepEqual(result, c.expect) {
+			t.Fatalf("case %d: expect %+v, got %+v", i, c.expect, result)
+		}
+	}
+}
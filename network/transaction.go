@@ -0,0 +1,274 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"github.com/stephenlyu/tds/date"
+	"github.com/stephenlyu/tds/entity"
+)
+
+const TRANS_PAGE_SIZE = 2000			// 服务器每次最多返回的成交数
+
+// AssignTransactionSeq sets Seq to the index of the transaction within its minute.
+func AssignTransactionSeq(transactions []Transaction) {
+	for i := range transactions {
+		if i > 0 && transactions[i].Date == transactions[i - 1].Date && transactions[i].Minute == transactions[i - 1].Minute {
+			transactions[i].Seq = transactions[i - 1].Seq + 1
+		} else {
+			transactions[i].Seq = 0
+		}
+	}
+}
+
+const TRANS_PAGE_RETRIES = 5			// 翻页期间有新成交时重新请求的次数
+
+// mergeTransPage prepends the older page to result, the last overlap transactions of page are the first ones of result.
+func mergeTransPage(page []Transaction, result []Transaction, overlap int) []Transaction {
+	if overlap > len(page) {
+		overlap = len(page)
+	}
+	return append(page[:len(page) - overlap:len(page) - overlap], result...)
+}
+
+// transShift returns how many transactions are appended since latest was fetched, current is the latest page now.
+// Whole pages are compared, so identical transactions next to each other do not confuse it.
+func transShift(latest []Transaction, current []Transaction) (int, bool) {
+	for shift := 0; shift < len(latest); shift++ {
+		n := len(latest) - shift
+		if n > len(current) {
+			continue
+		}
+
+		matched := true
+		for i := 0; i < n; i++ {
+			if latest[shift + i] != current[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return shift, true
+		}
+	}
+	return 0, false
+}
+
+// instantTransPager pages the instant transactions backward. New transactions of today shift the offsets,
+// the shift is measured by fetching the latest page again after every older page.
+type instantTransPager struct {
+	api *API
+	security *entity.Security
+	pageSize uint16
+
+	latest []Transaction			// 第一页
+	shift int						// 第一页之后的新成交数
+	result []Transaction			// 截止到第一页的成交
+	more bool
+}
+
+func newInstantTransPager(api *API, security *entity.Security, pageSize uint16) *instantTransPager {
+	return &instantTransPager{api: api, security: security, pageSize: pageSize, more: true}
+}
+
+func (this *instantTransPager) measureShift(ctx context.Context) (error, int) {
+	err, current := this.api.GetInstantTransactionContext(ctx, this.security, 0, this.pageSize)
+	if err != nil {
+		return err, 0
+	}
+
+	shift, ok := transShift(this.latest, current)
+	if !ok {
+		return fmt.Errorf("%w, more than %d new transactions", ErrTransChanged, len(this.latest)), 0
+	}
+	return nil, shift
+}
+
+// next prepends the next older page to result, more is false once the first transaction of the day is fetched.
+func (this *instantTransPager) next(ctx context.Context) error {
+	if !this.more {
+		return nil
+	}
+
+	if this.latest == nil {
+		err, page := this.api.GetInstantTransactionContext(ctx, this.security, 0, this.pageSize)
+		if err != nil {
+			return err
+		}
+		if page == nil {
+			page = []Transaction{}
+		}
+		this.latest = page
+		this.result = page
+		this.more = len(page) >= int(this.pageSize)
+		return nil
+	}
+
+	if len(this.result) > 0xffff - int(this.pageSize) {
+		return fmt.Errorf("%w, too many transactions: %d", ErrBadData, len(this.result))
+	}
+
+	for retryTimes := 0; ; retryTimes++ {
+		err, page := this.api.GetInstantTransactionContext(ctx, this.security, uint16(len(this.result)), this.pageSize)
+		if err != nil {
+			return err
+		}
+
+		err, shift := this.measureShift(ctx)
+		if err != nil {
+			return err
+		}
+
+		// 请求前后新成交数相同，说明这一页的偏移就是shift
+		if shift == this.shift {
+			this.result = mergeTransPage(page, this.result, shift)
+			this.more = len(page) >= int(this.pageSize)
+			return nil
+		}
+
+		this.shift = shift
+		if retryTimes + 1 >= TRANS_PAGE_RETRIES {
+			return fmt.Errorf("%w, %s", ErrTransChanged, this.security.String())
+		}
+	}
+}
+
+func (this *BizApi) GetAllTransactions(security *entity.Security, date uint32) (error, []Transaction) {
+	return this.GetAllTransactionsContext(context.Background(), security, date)
+}
+
+// GetAllTransactionsContext returns all the transactions of the day in time order with Seq assigned,
+// date 0 or today uses the instant transactions.
+func (this *BizApi) GetAllTransactionsContext(ctx context.Context, security *entity.Security, day uint32) (error, []Transaction) {
+	today := uint32(date.GetTodayInt())
+	if day == 0 || day == today {
+		pager := newInstantTransPager(this.api, security, TRANS_PAGE_SIZE)
+		for pager.more {
+			if err := pager.next(ctx); err != nil {
+				return err, nil
+			}
+		}
+
+		AssignTransactionSeq(pager.result)
+		return nil, pager.result
+	}
+
+	result := []Transaction{}
+	for {
+		if len(result) > 0xffff - TRANS_PAGE_SIZE {
+			return fmt.Errorf("%w, too many transactions: %d", ErrBadData, len(result)), nil
+		}
+
+		err, page := this.api.GetHistoryTransactionContext(ctx, security, day, uint16(len(result)), TRANS_PAGE_SIZE)
+		if err != nil {
+			return err, nil
+		}
+
+		result = append(page, result...)
+		if len(page) < TRANS_PAGE_SIZE {
+			break
+		}
+	}
+
+	AssignTransactionSeq(result)
+	return nil, result
+}
+
+func (this *BizApi) getTransactionFilePath(security *entity.Security, day uint32) string {
+	fileName := fmt.Sprintf("%s%s.json", strings.ToLower(security.GetExchange()), security.GetCode())
+	return filepath.Join(this.workDir, "T0002/trans", fmt.Sprintf("%d", day), fileName)
+}
+
+// LoadTransactions reads the transactions saved by DownloadTransactions.
+func (this *BizApi) LoadTransactions(security *entity.Security, day uint32) (error, []Transaction) {
+	data, err := ioutil.ReadFile(this.getTransactionFilePath(security, day))
+	if err != nil {
+		return err, nil
+	}
+
+	var result []Transaction
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err, nil
+	}
+	return nil, result
+}
+
+func (this *BizApi) DownloadTransactions(securities []*entity.Security, days []uint32) error {
+	return this.DownloadTransactionsContext(context.Background(), securities, days)
+}
+
+// DownloadTransactionsContext saves the transactions of every security and day under workDir/T0002/trans.
+// Files of past days are not downloaded again, failed downloads are skipped and the first error is returned.
+func (this *BizApi) DownloadTransactionsContext(ctx context.Context, securities []*entity.Security, days []uint32) error {
+	type task struct {
+		security *entity.Security
+		day uint32
+	}
+
+	today := uint32(date.GetTodayInt())
+	tasks := make(chan task)
+	go func() {
+		defer close(tasks)
+		for _, day := range days {
+			for _, security := range securities {
+				select {
+				case tasks <- task{security, day}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var lock sync.Mutex
+	var firstErr error
+	setErr := func(err error) {
+		lock.Lock()
+		defer lock.Unlock()
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	concurrency := this.api.options.MaxCap
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range tasks {
+				filePath := this.getTransactionFilePath(t.security, t.day)
+				if _, err := os.Stat(filePath); err == nil && t.day < today {
+					continue
+				}
+
+				err, transactions := this.GetAllTransactionsContext(ctx, t.security, t.day)
+				if err != nil {
+					setErr(err)
+					continue
+				}
+
+				os.MkdirAll(filepath.Dir(filePath), 0777)
+				bytes, _ := json.Marshal(transactions)
+				if err := ioutil.WriteFile(filePath, bytes, 0666); err != nil {
+					setErr(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return firstErr
+}
@@ -0,0 +1,75 @@
+package network
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAssignTransactionSeq(t *testing.T) {
+	transactions := []Transaction{
+		{Minute: 570}, {Minute: 570}, {Minute: 571}, {Minute: 571, Seq: 5}, {Minute: 571}, {Minute: 572},
+	}
+	AssignTransactionSeq(transactions)
+
+	seqs := []uint16{}
+	for _, t := range transactions {
+		seqs = append(seqs, t.Seq)
+	}
+	if !reflect.DeepEqual(seqs, []uint16{0, 1, 0, 1, 2, 0}) {
+		t.Fatalf("bad seqs: %v", seqs)
+	}
+}
+
+func TestMergeTransPage(t *testing.T) {
+	t1 := Transaction{Minute: 570, Price: 1000, Volume: 1}
+	t2 := Transaction{Minute: 570, Price: 1001, Volume: 2}
+	t3 := Transaction{Minute: 571, Price: 1002, Volume: 3}
+	t4 := Transaction{Minute: 571, Price: 1003, Volume: 4}
+
+	cases := []struct {
+		page []Transaction
+		result []Transaction
+		overlap int
+		expect []Transaction
+	}{
+		{[]Transaction{t1, t2}, []Transaction{}, 0, []Transaction{t1, t2}},
+		{[]Transaction{t1, t2}, []Transaction{t3, t4}, 0, []Transaction{t1, t2, t3, t4}},
+		{[]Transaction{t1, t2, t3}, []Transaction{t3, t4}, 1, []Transaction{t1, t2, t3, t4}},
+		{[]Transaction{t1, t2, t3}, []Transaction{t2, t3, t4}, 2, []Transaction{t1, t2, t3, t4}},
+		// 页尾和结果开头是两笔相同的成交，没有新成交时都保留
+		{[]Transaction{t1, t2, t3}, []Transaction{t3, t4}, 0, []Transaction{t1, t2, t3, t3, t4}},
+		{[]Transaction{}, []Transaction{t3, t4}, 0, []Transaction{t3, t4}},
+		{[]Transaction{t1}, []Transaction{t3, t4}, 2, []Transaction{t3, t4}},
+	}
+
+	for i, c := range cases {
+		if result := mergeTransPage(c.page, c.result, c.overlap); !reflect.DeepEqual(result, c.expect) {
+			t.Fatalf("case %d: expect %+v, got %+v", i, c.expect, result)
+		}
+	}
+}
+
+func TestTransShift(t *testing.T) {
+	t1 := Transaction{Minute: 570, Price: 1000, Volume: 1}
+	t2 := Transaction{Minute: 570, Price: 1001, Volume: 2}
+	t3 := Transaction{Minute: 571, Price: 1002, Volume: 3}
+
+	cases := []struct {
+		latest []Transaction
+		current []Transaction
+		shift int
+		ok bool
+	}{
+		{[]Transaction{t1, t2, t3}, []Transaction{t1, t2, t3}, 0, true},
+		{[]Transaction{t1, t1, t1}, []Transaction{t1, t1, t1}, 0, true},
+		{[]Transaction{t1, t2, t3}, []Transaction{t2, t3, t3}, 1, true},
+		{[]Transaction{t1, t2, t3}, []Transaction{t3, t1, t2}, 2, true},
+		{[]Transaction{t1, t2}, []Transaction{t3, t3}, 0, false},
+	}
+
+	for i, c := range cases {
+		if shift, ok := transShift(c.latest, c.current); shift != c.shift || ok != c.ok {
+			t.Fatalf("case %d: expect %d %v, got %d %v", i, c.shift, c.ok, shift, ok)
+		}
+	}
+}
@@ -13,7 +13,7 @@ import (
 
 var _ = Describe("GetInfoEx", func () {
 	It("test", func() {
-		err, api := network.CreateAPI(HOST)
+		err, api := createAPI()
 		if err != nil {
 			fmt.Println(err)
 			return
@@ -32,7 +32,7 @@ var _ = Describe("GetInfoEx", func () {
 
 var _ = Describe("GetMinuteData", func () {
 	It("test", func() {
-		err, api := network.CreateAPI(HOST)
+		err, api := createAPI()
 		if err != nil {
 			fmt.Println(err)
 			return
@@ -48,7 +48,7 @@ var _ = Describe("GetMinuteData", func () {
 
 var _ = Describe("GetPeriodHisData", func () {
 	It("test", func() {
-		err, api := network.CreateAPI(HOST)
+		err, api := createAPI()
 		if err != nil {
 			fmt.Println(err)
 			return
@@ -3,7 +3,6 @@ package test
 import (
 	. "github.com/onsi/ginkgo"
 
-	"github.com/stephenlyu/TdxProtocol/network"
 	"fmt"
 	"sort"
 	"time"
@@ -17,7 +16,7 @@ var _ = Describe("BizApiGetSZStockCodes", func () {
 	It("test", func() {
 		fmt.Println("test GetSZStockCodes...")
 
-		err, api := network.CreateBizApi(HOST_ONLY)
+		err, api := createBizApi()
 		if err != nil {
 			fmt.Println(err)
 			return
@@ -38,7 +37,7 @@ var _ = Describe("BizApiGetSZStockCodes", func () {
 var _ = Describe("BizApiGetInfoEx", func () {
 	It("test", func() {
 		fmt.Println("test GetInfoEx...")
-		err, api := network.CreateBizApi(HOST_ONLY)
+		err, api := createBizApi()
 		if err != nil {
 			fmt.Println(err)
 			return
@@ -71,7 +70,7 @@ var _ = Describe("BizApiGetInfoEx", func () {
 var _ = Describe("BizApiGetBids", func () {
 	It("test", func() {
 		fmt.Println("test GetBid...")
-		err, api := network.CreateBizApi(HOST_ONLY)
+		err, api := createBizApi()
 		if err != nil {
 			fmt.Println(err)
 			return
@@ -107,7 +106,7 @@ var _ = Describe("BizApiGetBids", func () {
 var _ = Describe("BizApiGetInfoEx", func () {
 	It("test", func() {
 		fmt.Println("test GetInfoEx...")
-		err, api := network.CreateBizApi(HOST_ONLY)
+		err, api := createBizApi()
 		if err != nil {
 			fmt.Println(err)
 			return
@@ -136,7 +135,7 @@ var _ = Describe("BizApiGetInfoEx", func () {
 var _ = Describe("BizApiGetFinance", func () {
 	It("test", func() {
 		fmt.Println("test GetFinance...")
-		err, api := network.CreateBizApi(HOST_ONLY)
+		err, api := createBizApi()
 		if err != nil {
 			fmt.Println(err)
 			return
@@ -163,7 +162,7 @@ var _ = Describe("BizApiGetFinance", func () {
 var _ = Describe("BizApiGetDayData", func () {
 	It("test", func() {
 		fmt.Println("test GetDayData...")
-		err, api := network.CreateBizApi(HOST_ONLY)
+		err, api := createBizApi()
 		if err != nil {
 			fmt.Println(err)
 			return
@@ -187,7 +186,7 @@ var _ = Describe("BizApiGetDayData", func () {
 var _ = Describe("BizApiGetMinuteData", func () {
 	It("test", func() {
 		fmt.Println("test GetMinuteData...")
-		err, api := network.CreateBizApi(HOST_ONLY)
+		err, api := createBizApi()
 		if err != nil {
 			fmt.Println(err)
 			return
@@ -206,7 +205,7 @@ var _ = Describe("BizApiGetMinuteData", func () {
 var _ = Describe("BizApiMinuteDataPerf", func () {
 	It("test", func() {
 		fmt.Println("test BizApiMinuteDataPerf...")
-		err, api := network.CreateBizApi(HOST_ONLY)
+		err, api := createBizApi()
 		if err != nil {
 			fmt.Println(err)
 			return
@@ -280,7 +279,7 @@ var _ = Describe("BizApiMinuteDataPerf", func () {
 var _ = Describe("BizApiGetFile", func () {
 	It("test", func() {
 		fmt.Println("test GetFile...")
-		err, api := network.CreateBizApi(HOST_ONLY)
+		err, api := createBizApi()
 		chk(err)
 		defer api.Cleanup()
 
@@ -294,7 +293,7 @@ var _ = Describe("BizApiGetFile", func () {
 var _ = Describe("BizApiGetNameData", func () {
 	It("test", func() {
 		fmt.Println("test get name data...")
-		err, api := network.CreateBizApi(HOST_ONLY)
+		err, api := createBizApi()
 		chk(err)
 		defer api.Cleanup()
 
@@ -309,7 +308,7 @@ var _ = Describe("BizApiGetNameData", func () {
 var _ = Describe("BizApiDownloadDayHisData", func () {
 	It("test", func() {
 		fmt.Println("test download day his data data...")
-		err, api := network.CreateBizApi(HOST_ONLY)
+		err, api := createBizApi()
 		chk(err)
 		defer api.Cleanup()
 
@@ -334,7 +333,7 @@ var _ = Describe("BizApiDownloadDayHisData", func () {
 var _ = Describe("BizApiDownloadM5HisData", func () {
 	It("test", func() {
 		fmt.Println("test download 5 minute his data data...")
-		err, api := network.CreateBizApi(HOST_ONLY)
+		err, api := createBizApi()
 		chk(err)
 		defer api.Cleanup()
 
@@ -361,7 +360,7 @@ var _ = Describe("BizApiDownloadM5HisData", func () {
 var _ = Describe("BizApiDownloadM1HisData", func () {
 	It("test", func() {
 		fmt.Println("test download 1 minute his data data...")
-		err, api := network.CreateBizApi(HOST_ONLY)
+		err, api := createBizApi()
 		chk(err)
 		defer api.Cleanup()
 
@@ -388,7 +387,7 @@ var _ = Describe("BizApiDownloadM1HisData", func () {
 var _ = Describe("BizApiDownloadInfoEx", func () {
 	It("test", func() {
 		fmt.Println("test downloading infoex data...")
-		err, api := network.CreateBizApi(HOST_ONLY)
+		err, api := createBizApi()
 		chk(err)
 		defer api.Cleanup()
 
@@ -0,0 +1,111 @@
+package test
+
+import (
+	"testing"
+	"encoding/hex"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"github.com/stephenlyu/TdxProtocol/network"
+)
+
+// buildResp 生成未压缩的响应数据
+func buildResp(seqId uint32, cmd uint16, body []byte) []byte {
+	data := make([]byte, network.RESP_HEADER_LEN + len(body))
+	copy(data, []byte{0xb1, 0xcb, 0x74, 0x00, 0x0c})
+	binary.LittleEndian.PutUint32(data[5:9], seqId)
+	binary.LittleEndian.PutUint16(data[10:12], cmd)
+	binary.LittleEndian.PutUint16(data[12:14], uint16(len(body)))
+	binary.LittleEndian.PutUint16(data[14:16], uint16(len(body)))
+	copy(data[network.RESP_HEADER_LEN:], body)
+	return data
+}
+
+func addRespSeeds(f *testing.F) {
+	for _, strHex := range []string{strings.Join(namesRespHexes, ""), bidRespHex, bidRespHex1} {
+		data, _ := hex.DecodeString(strHex)
+		f.Add(data)
+		f.Add(data[:len(data) / 2])
+	}
+
+	cmds := []uint16{
+		network.CMD_INFO_EX,
+		network.CMD_FINANCE,
+		network.CMD_NAMES_LEN,
+		network.CMD_NAMES,
+		network.CMD_BID,
+		network.CMD_PERIOD_DATA,
+		network.CMD_INSTANT_TRANS,
+		network.CMD_PERIOD_HIS_DATA,
+		network.CMD_HIS_TRANS,
+		network.CMD_GET_FILE_LEN,
+		network.CMD_GET_FILE_DATA,
+		network.CMD_MINUTE_TIME,
+		network.CMD_HIS_MINUTE_TIME,
+	}
+	bodies := []string{
+		"",
+		"0100",
+		"010000303030303031010000303030303031001a1b340101000000000000000000000000000000000000",
+		"02005e0282270a8c0100008f0201ffffff1f0a0100",
+		"0100a8fb9ad5078ff7ffffff1fb0fe0600000000cdcc4c3f",
+		"0100808080808080808001",
+	}
+	for _, cmd := range cmds {
+		for _, body := range bodies {
+			data, _ := hex.DecodeString(body)
+			f.Add(buildResp(1, cmd, data))
+		}
+	}
+}
+
+// 所有的解析器对任意输入都不应该panic
+// go test -run "^$" -fuzz FuzzRespParsers ./test/
+func FuzzRespParsers(f *testing.F) {
+	addRespSeeds(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		header := network.Header{}
+		parser := network.NewRespParser(data)
+		errSeqId, seqId := parser.GetSeqId()
+		errCmd, cmd := parser.GetCmd()
+		if (errSeqId == nil) != (len(data) >= network.RESP_HEADER_LEN) || (errCmd == nil) != (errSeqId == nil) {
+			t.Fatalf("bad header error, len: %d", len(data))
+		}
+		header.SeqId = seqId
+		header.Cmd = cmd
+
+		network.NewRespParser(data).Parse()
+		network.NewHeartBeatParser(header, data).Parse()
+		network.NewInstantTransParser(&network.InstantTransReq{Header: header}, data).Parse()
+		network.NewHisTransParser(&network.HisTransReq{Header: header}, data).Parse()
+		network.NewInfoExParser(&network.InfoExReq{Header: header}, data).Parse()
+		network.NewFinanceParser(&network.FinanceReq{Header: header}, data).Parse()
+		network.NewBidParser(&network.BidReq{Header: header}, data).Parse()
+		network.NewPeriodHisDataParser(&network.PeriodHisDataReq{Header: header}, data).Parse()
+		network.NewGetFileLenParser(header, data).Parse()
+		network.NewGetFileDataParser(header, data).Parse()
+		network.NewNamesParser(&network.NamesReq{Header: header}, data).Parse()
+		network.NewNamesLenParser(&network.NamesLenReq{Header: header}, data).Parse()
+		network.NewMinuteTimeParser(&network.MinuteTimeReq{Header: header}, data).Parse()
+		network.NewHisMinuteTimeParser(&network.HisMinuteTimeReq{Header: header}, data).Parse()
+		network.ParseSecurityList(network.MARKET_SZ, data)
+		network.ParseReq(data)
+
+		for _, period := range []uint16{network.PERIOD_MINUTE, network.PERIOD_MINUTE5, network.PERIOD_DAY} {
+			network.NewPeriodDataParser(&network.PeriodDataReq{Header: header, Period: period}, data).Parse()
+			network.NewIndexPeriodDataParser(&network.PeriodDataReq{Header: header, Period: period}, data).ParseIndex()
+		}
+
+		// 请求和解析器不匹配
+		if err, _ := network.NewHisTransParser(header, data).Parse(); !errors.Is(err, network.ErrBadReq) {
+			t.Fatalf("expect ErrBadReq, got %v", err)
+		}
+		if err, _ := network.NewIndexPeriodDataParser(&network.HisTransReq{Header: header}, data).ParseIndex(); !errors.Is(err, network.ErrBadReq) {
+			t.Fatalf("expect ErrBadReq, got %v", err)
+		}
+		if err, _ := network.NewPeriodDataParser(nil, data).Parse(); !errors.Is(err, network.ErrBadReq) {
+			t.Fatalf("expect ErrBadReq, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package test
+
+import (
+	"os"
+	"sync"
+	"github.com/stephenlyu/TdxProtocol/network"
+)
+
+// 设置环境变量TDX_RECORD=<file>记录所有请求和响应，设置TDX_REPLAY=<file>则使用记录的数据，不需要连接服务器
+const (
+	ENV_RECORD = "TDX_RECORD"
+	ENV_REPLAY = "TDX_REPLAY"
+)
+
+var (
+	recorderOnce sync.Once
+	recorder *network.Recorder
+	replayOnce sync.Once
+	captures []*network.Capture
+)
+
+func getRecorder() *network.Recorder {
+	recorderOnce.Do(func() {
+		path := os.Getenv(ENV_RECORD)
+		if path == "" {
+			return
+		}
+		var err error
+		err, recorder = network.CreateRecorder(path)
+		chk(err)
+	})
+	return recorder
+}
+
+func getOptions() *network.Options {
+	options := network.DefaultOptions()
+	options.Hosts = []string{HOST}
+
+	replayOnce.Do(func() {
+		path := os.Getenv(ENV_REPLAY)
+		if path == "" {
+			return
+		}
+		var err error
+		err, captures = network.LoadCaptures(path)
+		chk(err)
+	})
+	if captures != nil {
+		options.Dialer = network.NewReplayDialer(captures)
+	}
+	return options
+}
+
+func createAPI() (error, *network.API) {
+	err, api := network.CreateAPIWithOptions(getOptions())
+	if err != nil {
+		return err, nil
+	}
+	if r := getRecorder(); r != nil {
+		api.SetRecorder(r)
+	}
+	return nil, api
+}
+
+func createBizApi() (error, *network.BizApi) {
+	err, api := network.CreateBizApiWithOptions(getOptions())
+	if err != nil {
+		return err, nil
+	}
+	if r := getRecorder(); r != nil {
+		api.SetRecorder(r)
+	}
+	return nil, api
+}